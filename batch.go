@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/internal/core"
+)
+
+// BatchLoader loads the values of keys on a miss. Keys missing from the
+// returned map don't exist; they are left out of the result and not stored.
+type BatchLoader[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// ErrNotFound is returned by GetOrLoad when it waited on a batch load that
+// didn't return the key.
+var ErrNotFound = errors.New("key not found by loader")
+
+// groupByShard splits keys by shard, indexed like c.shards.
+func (c *Cache[K, V]) groupByShard(keys []K) [][]K {
+	groups := make([][]K, len(c.shards))
+	for _, k := range keys {
+		_, idx := c.shardFor(k)
+		groups[idx] = append(groups[idx], k)
+	}
+	return groups
+}
+
+// GetMany returns the values of the keys that are in the cache. Keys are
+// grouped by shard, so each shard is locked once.
+func (c *Cache[K, V]) GetMany(keys []K) map[K]V {
+	out := make(map[K]V, len(keys))
+	if c.closed() {
+		return out
+	}
+	for idx, group := range c.groupByShard(keys) {
+		if len(group) == 0 {
+			continue
+		}
+		hits, refresh := c.shards[idx].GetMany(group, out)
+		c.stats.Hits.Add(uint64(hits))
+		c.stats.Misses.Add(uint64(len(group) - hits))
+		if c.loader != nil {
+			for _, k := range refresh {
+				c.refresh(k)
+			}
+		}
+	}
+	return out
+}
+
+// pendingLoad is a key GetManyOrLoad started a call for.
+type pendingLoad[K comparable, V any] struct {
+	key K
+	g   *flightGroup[K, V]
+	cl  *call[V]
+}
+
+// GetManyOrLoad is like GetMany, but calls batchLoader once for the missing
+// keys and stores what it returns. Loads are deduplicated per key with
+// GetOrLoad and other GetManyOrLoad calls: keys already loading are waited
+// on instead of loaded again.
+// Returns the values found and the first error of the loads, values that
+// were loaded successfully are returned even when others failed.
+func (c *Cache[K, V]) GetManyOrLoad(ctx context.Context, keys []K, batchLoader BatchLoader[K, V]) (map[K]V, error) {
+	if c.closed() {
+		return map[K]V{}, ErrClosed
+	}
+	out := c.GetMany(keys)
+	if len(out) == len(keys) {
+		return out, nil
+	}
+	if batchLoader == nil {
+		return out, errors.New("batch loader must not be nil")
+	}
+
+	var firstErr error
+	var mine []pendingLoad[K, V]
+	var waits []pendingLoad[K, V]
+	seen := make(map[K]struct{}, len(keys)-len(out))
+	for _, k := range keys {
+		if _, ok := out[k]; ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		shard, idx := c.shardFor(k)
+		g := c.flights[idx]
+		g.mu.Lock()
+		if f, ok := g.failed[k]; ok && time.Now().Before(f.expiresAt) {
+			g.mu.Unlock()
+			if firstErr == nil {
+				firstErr = f.err
+			}
+			continue
+		}
+		if cl, ok := g.calls[k]; ok {
+			g.mu.Unlock()
+			waits = append(waits, pendingLoad[K, V]{k, g, cl})
+			continue
+		}
+		// a load may have finished since the miss above, peek so the
+		// policy doesn't see the key twice
+		if val, hit := shard.PeekLive(k); hit {
+			g.mu.Unlock()
+			out[k] = val
+			continue
+		}
+		cl := &call[V]{done: make(chan struct{})}
+		g.calls[k] = cl
+		g.mu.Unlock()
+		mine = append(mine, pendingLoad[K, V]{k, g, cl})
+	}
+
+	if len(mine) > 0 {
+		c.loadBatch(ctx, mine, batchLoader)
+		for _, p := range mine {
+			if p.cl.err == nil {
+				out[p.key] = p.cl.val
+			} else if !errors.Is(p.cl.err, ErrNotFound) && firstErr == nil {
+				firstErr = p.cl.err
+			}
+		}
+	}
+
+	for _, p := range waits {
+		select {
+		case <-p.cl.done:
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+		if p.cl.err == nil {
+			out[p.key] = p.cl.val
+		} else if !errors.Is(p.cl.err, ErrNotFound) && firstErr == nil {
+			firstErr = p.cl.err
+		}
+	}
+	return out, firstErr
+}
+
+// loadBatch calls batchLoader for the keys of calls, stores the results and
+// releases the waiters.
+func (c *Cache[K, V]) loadBatch(ctx context.Context, calls []pendingLoad[K, V], batchLoader BatchLoader[K, V]) {
+	keys := make([]K, len(calls))
+	for i, p := range calls {
+		keys[i] = p.key
+		p.cl.err = errLoaderPanicked // overwritten unless batchLoader panics
+	}
+	defer func() {
+		for _, p := range calls {
+			c.finish(p.g, p.key, p.cl)
+		}
+	}()
+
+	vals, err := batchLoader(ctx, keys)
+	loaded := make(map[K]V, len(calls))
+	for _, p := range calls {
+		if err != nil {
+			p.cl.err = err
+			continue
+		}
+		val, ok := vals[p.key]
+		if !ok {
+			p.cl.err = ErrNotFound
+			continue
+		}
+		p.cl.val, p.cl.err = val, nil
+		loaded[p.key] = val
+	}
+	if len(loaded) > 0 {
+		// grouped by shard, each shard is locked once
+		c.SetMany(loaded)
+	}
+}
+
+// SetMany stores all items with the default TTL. Keys are grouped by shard
+// and each shard is locked once. Returns whether each key was stored and
+// the total number of evicted entries.
+func (c *Cache[K, V]) SetMany(items map[K]V) (success map[K]bool, evicted int) {
+	return c.setMany(items, func(s *core.Shard[K, V], keys []K, vals []V, ok []bool) int {
+		return s.SetMany(keys, vals, ok)
+	})
+}
+
+// SetManyWithTTL is like SetMany, with ttl for all items.
+func (c *Cache[K, V]) SetManyWithTTL(items map[K]V, ttl time.Duration) (success map[K]bool, evicted int) {
+	return c.setMany(items, func(s *core.Shard[K, V], keys []K, vals []V, ok []bool) int {
+		return s.SetManyWithTTL(keys, vals, ttl, ok)
+	})
+}
+
+func (c *Cache[K, V]) setMany(
+	items map[K]V,
+	set func(s *core.Shard[K, V], keys []K, vals []V, ok []bool) int,
+) (success map[K]bool, evicted int) {
+	if c.closed() {
+		return map[K]bool{}, 0
+	}
+	keys := make([][]K, len(c.shards))
+	vals := make([][]V, len(c.shards))
+	for k, v := range items {
+		_, idx := c.shardFor(k)
+		keys[idx] = append(keys[idx], k)
+		vals[idx] = append(vals[idx], v)
+	}
+
+	success = make(map[K]bool, len(items))
+	for idx := range c.shards {
+		if len(keys[idx]) == 0 {
+			continue
+		}
+		ok := make([]bool, len(keys[idx]))
+		evicted += set(c.shards[idx], keys[idx], vals[idx], ok)
+		for i, k := range keys[idx] {
+			success[k] = ok[i]
+		}
+	}
+	c.stats.Evictions.Add(uint64(evicted))
+	return success, evicted
+}
+
+// DelMany removes keys, locking each shard once. Returns whether each key
+// was found.
+func (c *Cache[K, V]) DelMany(keys []K) (deleted map[K]bool) {
+	if c.closed() {
+		return map[K]bool{}
+	}
+	deleted = make(map[K]bool, len(keys))
+	total := 0
+	for idx, group := range c.groupByShard(keys) {
+		if len(group) == 0 {
+			continue
+		}
+		ok := make([]bool, len(group))
+		total += c.shards[idx].DelMany(group, ok)
+		for i, k := range group {
+			deleted[k] = deleted[k] || ok[i]
+		}
+	}
+	c.stats.Deletes.Add(uint64(total))
+	return deleted
+}
@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_GetMany(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 10 {
+		c.Set(i, i*10)
+	}
+
+	got := c.GetMany([]int{1, 5, 9, 42})
+	if len(got) != 3 {
+		t.Fatalf("expected 3 values, got %d", len(got))
+	}
+	for _, k := range []int{1, 5, 9} {
+		if got[k] != k*10 {
+			t.Errorf("expected %d, got %d", k*10, got[k])
+		}
+	}
+	if stats := c.Stats(); stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("expected 3 hits and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+
+	if got := c.GetMany(nil); len(got) != 0 {
+		t.Errorf("expected empty result, got %v", got)
+	}
+}
+
+func TestCache_GetManyOrLoad(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set(1, 10)
+
+	var requested [][]int
+	loader := func(ctx context.Context, keys []int) (map[int]int, error) {
+		requested = append(requested, slices.Clone(keys))
+		out := make(map[int]int)
+		for _, k := range keys {
+			if k != 99 { // 99 doesn't exist
+				out[k] = k * 10
+			}
+		}
+		return out, nil
+	}
+
+	got, err := c.GetManyOrLoad(context.Background(), []int{1, 2, 3, 3, 99}, loader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[1] != 10 || got[2] != 20 || got[3] != 30 {
+		t.Errorf("expected {1:10 2:20 3:30}, got %v", got)
+	}
+	// one call, only missing keys, no duplicates
+	if len(requested) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(requested))
+	}
+	slices.Sort(requested[0])
+	if !slices.Equal(requested[0], []int{2, 3, 99}) {
+		t.Errorf("expected [2 3 99], got %v", requested[0])
+	}
+	// loaded keys are stored, missing keys are not
+	if _, ok := c.Peek(2); !ok {
+		t.Errorf("expected key=2 stored")
+	}
+	if _, ok := c.Peek(99); ok {
+		t.Errorf("expected key=99 not stored")
+	}
+
+	// all hits, loader isn't called
+	c.GetManyOrLoad(context.Background(), []int{1, 2, 3}, loader)
+	if len(requested) != 1 {
+		t.Errorf("expected 1 call, got %d", len(requested))
+	}
+}
+
+func TestCache_GetManyOrLoad_PolicyHooks(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := &missCounter{Policy: c.shards[0].Policy}
+	c.shards[0].Policy = p
+	loader := func(ctx context.Context, keys []int) (map[int]int, error) {
+		out := make(map[int]int, len(keys))
+		for _, k := range keys {
+			out[k] = k
+		}
+		return out, nil
+	}
+
+	if _, err := c.GetManyOrLoad(context.Background(), []int{1, 2}, loader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.misses != 2 || p.hits != 0 {
+		t.Errorf("expected 2 misses and 0 hits, got %d and %d", p.misses, p.hits)
+	}
+}
+
+// Loaded values are stored per shard like SetMany, evictions are counted.
+func TestCache_GetManyOrLoad_Evictions(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetCapacity(2).SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set(1, 1)
+	c.Set(2, 2)
+	loader := func(ctx context.Context, keys []int) (map[int]int, error) {
+		out := make(map[int]int, len(keys))
+		for _, k := range keys {
+			out[k] = k
+		}
+		return out, nil
+	}
+
+	if _, err := c.GetManyOrLoad(context.Background(), []int{3, 4, 5}, loader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := c.Stats().Evictions; n != 3 {
+		t.Errorf("expected 3 evictions, got %d", n)
+	}
+	if l := c.Len(); l != 2 {
+		t.Errorf("expected len=2, got %d", l)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+func TestCache_GetManyOrLoad_Error(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set(1, 10)
+	errLoad := errors.New("load failed")
+
+	got, err := c.GetManyOrLoad(context.Background(), []int{1, 2}, func(ctx context.Context, keys []int) (map[int]int, error) {
+		return nil, errLoad
+	})
+	if !errors.Is(err, errLoad) {
+		t.Fatalf("expected errLoad, got %v", err)
+	}
+	// hits are still returned
+	if len(got) != 1 || got[1] != 10 {
+		t.Errorf("expected {1:10}, got %v", got)
+	}
+
+	if _, err := c.GetManyOrLoad(context.Background(), []int{2}, nil); err == nil {
+		t.Errorf("expected error for nil loader, got nil")
+	}
+}
+
+// keys loading through GetOrLoad are waited on, not loaded again
+func TestCache_GetManyOrLoad_Singleflight(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, time.Duration, error) {
+			close(started)
+			<-release
+			return 10, 0, nil
+		})
+	}()
+	<-started
+
+	var batchKeys atomic.Value
+	loader := func(ctx context.Context, keys []int) (map[int]int, error) {
+		batchKeys.Store(slices.Clone(keys))
+		return map[int]int{2: 20}, nil
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	got, err := c.GetManyOrLoad(context.Background(), []int{1, 2}, loader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	if got[1] != 10 || got[2] != 20 {
+		t.Errorf("expected {1:10 2:20}, got %v", got)
+	}
+	if keys := batchKeys.Load().([]int); !slices.Equal(keys, []int{2}) {
+		t.Errorf("expected batch of [2], got %v", keys)
+	}
+}
+
+// GetOrLoad waiting on a batch load that doesn't return the key
+func TestCache_GetOrLoad_BatchNotFound(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.GetManyOrLoad(context.Background(), []int{1}, func(ctx context.Context, keys []int) (map[int]int, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	_, err = c.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, time.Duration, error) {
+		t.Error("expected to wait on the batch load")
+		return 0, 0, nil
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	<-done
+}
+
+func TestCache_SetMany(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetCapacity(12).
+		SetNumShards(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := make(map[int]int)
+	for i := range 6 {
+		items[i] = i * 10
+	}
+
+	success, evicted := c.SetMany(items)
+	if len(success) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(success))
+	}
+	for k, ok := range success {
+		if !ok {
+			t.Errorf("expected key=%d stored", k)
+		}
+		if v, _ := c.Peek(k); v != k*10 {
+			t.Errorf("expected %d, got %d", k*10, v)
+		}
+	}
+
+	// 6 per shard, so the first batch always fits
+	if evicted != 0 {
+		t.Errorf("expected evicted=0, got %d", evicted)
+	}
+
+	// 16 keys don't fit, some shard has to evict
+	more := make(map[int]int)
+	for i := 6; i < 16; i++ {
+		more[i] = i
+	}
+	_, evicted = c.SetMany(more)
+	if evicted == 0 {
+		t.Errorf("expected evictions, got 0")
+	}
+	if stats := c.Stats(); stats.Evictions != uint64(evicted) {
+		t.Errorf("expected evictions=%d, got %d", evicted, stats.Evictions)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+func TestCache_SetManyWithTTL(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	success, _ := c.SetManyWithTTL(map[int]int{1: 1, 2: 2}, -time.Second)
+	if !success[1] || !success[2] {
+		t.Fatalf("expected all stored, got %v", success)
+	}
+	if got := c.GetMany([]int{1, 2}); len(got) != 0 {
+		t.Errorf("expected all expired, got %v", got)
+	}
+
+	c.SetManyWithTTL(map[int]int{3: 3}, time.Hour)
+	if _, ok := c.Get(3); !ok {
+		t.Errorf("expected key=3 found")
+	}
+}
+
+func TestCache_DelMany(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetMany(map[int]int{1: 1, 2: 2, 3: 3})
+
+	deleted := c.DelMany([]int{1, 2, 2, 42})
+	if !deleted[1] || !deleted[2] || deleted[42] {
+		t.Errorf("expected {1:true 2:true 42:false}, got %v", deleted)
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected len=1, got %d", c.Len())
+	}
+	if stats := c.Stats(); stats.Deletes != 2 {
+		t.Errorf("expected deletes=2, got %d", stats.Deletes)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
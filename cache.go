@@ -2,29 +2,72 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jeltjongsma/go-cache/internal/core"
 	"github.com/jeltjongsma/go-cache/pkg/hasher"
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 type Cache[K comparable, V any] struct {
-	shards []*core.Shard[K, V]
-	hasher *hasher.Hasher[K]
-	opts   *Options[K]
-	stats  *core.Stats
+	shards   []*core.Shard[K, V]
+	hasher   *hasher.Hasher[K]
+	opts     *Options[K]
+	stats    *core.Stats
+	flights  []*flightGroup[K, V] // in-flight loads, sharded like shards
+	loader   Loader[K, V]         // refreshes entries, see SetLoader
+	onEvict  func(K, V, RemovalReason)
+	onRemove func(K, V, RemovalReason)
+	janitor  *core.Janitor[K, V] // nil without a default TTL
+
+	// see Shutdown
+	lifecycle   sync.RWMutex // orders closing with starting background loads
+	closing     atomic.Bool
+	done        chan struct{} // closed when the shutdown finished
+	loads       sync.WaitGroup
+	loadCtx     context.Context // ctx of background loads
+	cancelLoads context.CancelFunc
 }
 
+// RemovalReason tells OnEvict and OnRemove callbacks why an entry left the
+// cache.
+type RemovalReason = core.RemovalReason
+
+const (
+	ReasonEvicted  = core.ReasonEvicted  // evicted to make room
+	ReasonExpired  = core.ReasonExpired  // TTL ran out
+	ReasonDeleted  = core.ReasonDeleted  // removed by Del
+	ReasonReplaced = core.ReasonReplaced // value overwritten by a set
+	ReasonFlushed  = core.ReasonFlushed  // removed by Flush
+)
+
+// SweepMode selects how the janitor removes expired entries, see
+// Options.SetJanitorSweepMode.
+type SweepMode = core.SweepMode
+
+const (
+	SweepLocked      = core.SweepLocked      // all expired entries of a shard under one lock
+	SweepIncremental = core.SweepIncremental // bounded batches, adaptively repeated
+)
+
 // Cache is configured through *Options[K].
 // Checks if the input is valid and returns an error on invalid options:
 //   - Capacity must be postive, clamped to 0 on input < 0  (cap == 0 means no limit)
+//   - MaxCost must be positive, clamped to 0 on input < 0 (0 means no limit)
+//   - MaxMemory must be positive, clamped to 0 on input < 0 (0 means no limit)
 //   - NumShards must be greater than 0 and an exponential of 2 (nShards = 2^k)
+//   - Capacity, MaxCost and MaxMemory are split over the shards, so when set
+//     they must be at least NumShards
 //   - Hasher cannot be nil
+//   - Policy must be built in or registered (see policies.Register), unless
+//     PolicyFactory is set
 func NewCache[K comparable, V any](
 	opts *Options[K],
 ) (*Cache[K, V], error) {
@@ -32,59 +75,118 @@ func NewCache[K comparable, V any](
 	if opts.Capacity < 0 {
 		opts.Capacity = 0
 	}
+	if opts.MaxCost < 0 {
+		opts.MaxCost = 0
+	}
+	if opts.MaxMemory < 0 {
+		opts.MaxMemory = 0
+	}
 	if opts.NumShards <= 0 {
 		return nil, fmt.Errorf("num shards (%d) must be >0", opts.NumShards)
 	}
 	if uint64(opts.NumShards)&uint64((opts.NumShards-1)) != 0 {
 		return nil, fmt.Errorf("num shards (%d) must be exponential of 2", opts.NumShards)
 	}
+	// a share of 0 would mean no limit
+	if opts.Capacity > 0 && opts.Capacity < opts.NumShards {
+		return nil, fmt.Errorf("capacity (%d) must be >= num shards (%d)", opts.Capacity, opts.NumShards)
+	}
+	if opts.MaxCost > 0 && opts.MaxCost < int64(opts.NumShards) {
+		return nil, fmt.Errorf("max cost (%d) must be >= num shards (%d)", opts.MaxCost, opts.NumShards)
+	}
+	if opts.MaxMemory > 0 && opts.MaxMemory < int64(opts.NumShards) {
+		return nil, fmt.Errorf("max memory (%d) must be >= num shards (%d)", opts.MaxMemory, opts.NumShards)
+	}
 	if opts.Hasher == nil {
 		return nil, errors.New("hasher must not be nil")
 	}
+	if opts.ExpiryIndex == "" {
+		opts.ExpiryIndex = ttl_queue.TypeHeap
+	}
+	if _, ok := ttl_queue.NewIndex[K](opts.ExpiryIndex); !ok {
+		return nil, fmt.Errorf("invalid expiry index: %s", opts.ExpiryIndex)
+	}
+	expiryIndex := opts.ExpiryIndex
+	if opts.DefaultTTL == 0 {
+		// nothing expires, don't pay for indexing
+		expiryIndex = ttl_queue.TypeNone
+	}
 
 	// init shards
 	shards := make([]*core.Shard[K, V], opts.NumShards)
 	shardCap := opts.Capacity / int(opts.NumShards)
-	for i := range opts.NumShards {
-		var pol policies.Policy[K]
-		switch opts.Policy {
-		case policies.TypeFIFO:
-			pol = policies.NewFIFO[K]()
-		case policies.TypeLRU:
-			pol = policies.NewLRU[K]()
-		default:
+	factory := opts.PolicyFactory
+	if factory == nil {
+		newPolicy, ok := policies.Lookup[K](opts.Policy)
+		if !ok {
 			return nil, fmt.Errorf("invalid policy type: %s", opts.Policy)
 		}
+		cfg := policies.Config[K]{Capacity: shardCap, Hasher: opts.Hasher}
+		factory = func() policies.Policy[K] { return newPolicy(cfg) }
+	}
+	for i := range opts.NumShards {
+		pol := factory()
 		shards[i] = core.InitShard[K, V](pol, shardCap, opts.DefaultTTL)
+		shards[i].SetMaxCost(opts.MaxCost / int64(opts.NumShards))
+		shards[i].SetMaxMemory(opts.MaxMemory / int64(opts.NumShards))
+		shards[i].SetRefresh(opts.RefreshAfter, opts.StaleTTL)
+		idx, _ := ttl_queue.NewIndex[K](expiryIndex)
+		shards[i].SetExpiryIndex(idx)
 	}
+	var janitor *core.Janitor[K, V]
 	if opts.DefaultTTL != 0 {
-		for i := range opts.NumShards {
-			core.StartJanitor(shards[i], 10*time.Second)
-		}
+		janitor = core.StartJanitor(shards, core.JanitorConfig{
+			Interval:     opts.JanitorInterval,
+			Budget:       opts.JanitorBudget,
+			MaxLockHold:  opts.JanitorMaxLockHold,
+			Mode:         opts.JanitorSweepMode,
+			BatchSize:    opts.JanitorBatchSize,
+			ExpiredRatio: opts.JanitorExpiredRatio,
+		})
+	}
+
+	flights := make([]*flightGroup[K, V], opts.NumShards)
+	for i := range flights {
+		flights[i] = newFlightGroup[K, V]()
 	}
 
+	loadCtx, cancelLoads := context.WithCancel(context.Background())
+
 	// init cache
 	return &Cache[K, V]{
-		shards: shards,
-		hasher: opts.Hasher,
-		opts:   opts,
-		stats:  &core.Stats{},
+		shards:      shards,
+		hasher:      opts.Hasher,
+		opts:        opts,
+		stats:       &core.Stats{},
+		flights:     flights,
+		janitor:     janitor,
+		done:        make(chan struct{}),
+		loadCtx:     loadCtx,
+		cancelLoads: cancelLoads,
 	}, nil
 }
 
-// SetPolicy sets a custom policy.
+// SetPolicy sets a custom policy, f is called once per shard.
 // Will return an error if the cache is not empty.
-func (c *Cache[K, V]) SetPolicy(p policies.Policy[K]) error {
-	if c.Len() != 0 {
-		return errors.New("cannot set policy on non-empty cache")
+func (c *Cache[K, V]) SetPolicy(f policies.PolicyFactory[K]) error {
+	if f == nil {
+		return errors.New("policy factory must not be nil")
 	}
-	for _, s := range c.shards {
-		s.Policy = p
+	ps := make([]policies.Policy[K], len(c.shards))
+	for i := range ps {
+		ps[i] = f()
 	}
+	if !core.SetPolicies(c.shards, ps) {
+		return errors.New("cannot set policy on non-empty cache")
+	}
+	c.opts.PolicyFactory = f
 	return nil
 }
 
 func (c *Cache[K, V]) SetWithTTL(key K, val V, ttl time.Duration) (success bool, evicted int) {
+	if c.closed() {
+		return
+	}
 	shard, _ := c.shardFor(key)
 	success, evicted = shard.SetWithTTL(key, val, ttl)
 	c.stats.Evictions.Add(uint64(evicted))
@@ -92,15 +194,94 @@ func (c *Cache[K, V]) SetWithTTL(key K, val V, ttl time.Duration) (success bool,
 }
 
 func (c *Cache[K, V]) Set(key K, val V) (success bool, evicted int) {
+	if c.closed() {
+		return
+	}
 	shard, _ := c.shardFor(key)
 	success, evicted = shard.Set(key, val)
 	c.stats.Evictions.Add(uint64(evicted))
 	return
 }
 
+// SetWithCost stores key with an explicit cost instead of the one computed
+// by the weigher. Fails when cost exceeds the cost budget of a shard.
+func (c *Cache[K, V]) SetWithCost(key K, val V, cost int64) (success bool, evicted int) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	success, evicted = shard.SetWithCost(key, val, cost)
+	c.stats.Evictions.Add(uint64(evicted))
+	return
+}
+
+// SetWeigher sets the function that computes the cost of entries stored with
+// Set and SetWithTTL. Without a weigher every entry costs 1.
+// Costs of entries already stored are not recomputed.
+func (c *Cache[K, V]) SetWeigher(w func(K, V) int64) {
+	for _, s := range c.shards {
+		s.SetWeigher(w)
+	}
+}
+
+// SetOnEvict sets the callback for entries the cache removed by itself:
+// evicted to make room or expired. nil disables it.
+// Callbacks run after the shard lock is released, on the goroutine that
+// caused the removal (a janitor for sweeps), so they may use the cache.
+// They must not call Close or Shutdown: those wait for the janitor and
+// background refreshes, which may be the goroutine running the callback.
+// Set callbacks before using the cache, setting them isn't synchronized with
+// each other.
+func (c *Cache[K, V]) SetOnEvict(f func(key K, val V, reason RemovalReason)) {
+	c.onEvict = f
+	c.setRemovalListener()
+}
+
+// SetOnRemove sets the callback for every entry that leaves the cache,
+// whatever the reason. See SetOnEvict for how callbacks are delivered.
+func (c *Cache[K, V]) SetOnRemove(f func(key K, val V, reason RemovalReason)) {
+	c.onRemove = f
+	c.setRemovalListener()
+}
+
+func (c *Cache[K, V]) setRemovalListener() {
+	onEvict, onRemove := c.onEvict, c.onRemove
+	var listener func(K, V, RemovalReason)
+	if onEvict != nil || onRemove != nil {
+		listener = func(k K, v V, reason RemovalReason) {
+			if onEvict != nil && (reason == ReasonEvicted || reason == ReasonExpired) {
+				onEvict(k, v, reason)
+			}
+			if onRemove != nil {
+				onRemove(k, v, reason)
+			}
+		}
+	}
+	for _, s := range c.shards {
+		s.SetRemovalListener(listener)
+	}
+}
+
+// SetSizer sets the function that estimates the bytes of a value when a
+// memory budget is set. Without a sizer values are estimated by walking them.
+// Values passed to a set are measured before the shard is locked, only values
+// computed under the lock (Compute, Update) are measured while holding it.
+func (c *Cache[K, V]) SetSizer(f func(V) int64) {
+	for _, s := range c.shards {
+		s.SetSizer(f)
+	}
+}
+
 func (c *Cache[K, V]) Get(key K) (val V, hit bool) {
+	if c.closed() {
+		return
+	}
 	shard, _ := c.shardFor(key)
-	val, hit = shard.Get(key)
+	var refresh bool
+	val, hit, refresh = shard.GetRefresh(key)
+	if refresh && c.loader != nil {
+		c.refresh(key)
+	}
 	if hit {
 		c.stats.Hits.Add(1)
 	} else {
@@ -111,11 +292,18 @@ func (c *Cache[K, V]) Get(key K) (val V, hit bool) {
 
 // Peek is like get but won't affect eviction policy.
 func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	if c.closed() {
+		var zero V
+		return zero, false
+	}
 	shard, _ := c.shardFor(key)
 	return shard.Peek(key)
 }
 
 func (c *Cache[K, V]) Del(key K) (success bool) {
+	if c.closed() {
+		return
+	}
 	shard, _ := c.shardFor(key)
 	if success = shard.Del(key); success {
 		c.stats.Deletes.Add(1)
@@ -132,6 +320,9 @@ func (c *Cache[K, V]) Len() int {
 }
 
 func (c *Cache[K, V]) Flush() {
+	if c.closed() {
+		return
+	}
 	numWorkers := min(runtime.GOMAXPROCS(0), c.opts.NumShards)
 	jobs := make(chan int, numWorkers)
 	var wg sync.WaitGroup
@@ -157,15 +348,29 @@ func (c *Cache[K, V]) Flush() {
 }
 
 func (c *Cache[K, V]) Stats() *core.StatsSnapshot {
-	return &core.StatsSnapshot{
-		Hits:      c.stats.Hits.Load(),
-		Misses:    c.stats.Misses.Load(),
-		Evictions: c.stats.Evictions.Load(),
-		Deletes:   c.stats.Deletes.Load(),
-		Flushes:   c.stats.Flushes.Load(),
+	snap := &core.StatsSnapshot{
+		Hits:        c.stats.Hits.Load(),
+		Misses:      c.stats.Misses.Load(),
+		Evictions:   c.stats.Evictions.Load(),
+		Deletes:     c.stats.Deletes.Load(),
+		Flushes:     c.stats.Flushes.Load(),
+		ShardCost:   make([]int64, len(c.shards)),
+		ShardMemory: make([]int64, len(c.shards)),
+	}
+	for i, s := range c.shards {
+		snap.ShardCost[i] = s.Cost()
+		snap.Cost += snap.ShardCost[i]
+		snap.ShardMemory[i] = s.Memory()
+		snap.Memory += snap.ShardMemory[i]
+	}
+	if c.janitor != nil {
+		snap.Janitor = c.janitor.Stats()
 	}
+	return snap
 }
 
+// shardFor returns the shard of key and its index, which is also the index
+// of the key's flight group.
 func (c *Cache[K, V]) shardFor(key K) (*core.Shard[K, V], uint64) {
 	idx := c.hasher.Hash(key) % (uint64(len(c.shards)))
 	return c.shards[idx], idx
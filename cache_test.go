@@ -1,10 +1,15 @@
 package cache
 
 import (
+	"math/rand"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/jeltjongsma/go-cache/internal/core"
 	"github.com/jeltjongsma/go-cache/pkg/hasher"
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 func TestCache_New(t *testing.T) {
@@ -25,6 +30,78 @@ func TestCache_New(t *testing.T) {
 			NumShards: 2,
 			Hasher:    hasher.NewHasher[int](nil),
 		}, false},
+		{"cap < num shards", &Options[int]{
+			Capacity:  1,
+			Policy:    policies.TypeFIFO,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, true},
+		{"max cost < num shards", &Options[int]{
+			MaxCost:   1,
+			Policy:    policies.TypeFIFO,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, true},
+		{"max memory < num shards", &Options[int]{
+			MaxMemory: 1,
+			Policy:    policies.TypeFIFO,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, true},
+		{"lfu policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeLFU,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"arc policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeARC,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"w-tinylfu policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeWTinyLFU,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"sieve policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeSIEVE,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"s3-fifo policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeS3FIFO,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"clock policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeCLOCK,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"clock-pro policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeCLOCKPro,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"sampled-lru policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeSampledLRU,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
+		{"sampled-lfu policy", &Options[int]{
+			Capacity:  2,
+			Policy:    policies.TypeSampledLFU,
+			NumShards: 2,
+			Hasher:    hasher.NewHasher[int](nil),
+		}, false},
 		{"incorrect num shards", &Options[int]{
 			Capacity:  2,
 			Policy:    policies.TypeFIFO,
@@ -37,6 +114,13 @@ func TestCache_New(t *testing.T) {
 			NumShards: 2,
 			Hasher:    hasher.NewHasher[int](nil),
 		}, true},
+		{"policy factory", &Options[int]{
+			Capacity:      2,
+			Policy:        "wrong policy",
+			PolicyFactory: func() policies.Policy[int] { return policies.NewLRU[int]() },
+			NumShards:     2,
+			Hasher:        hasher.NewHasher[int](nil),
+		}, false},
 		{"nil hasher", &Options[int]{
 			Capacity:  2,
 			Policy:    policies.TypeFIFO,
@@ -79,19 +163,95 @@ func TestCache_New(t *testing.T) {
 	}
 }
 
+func TestCache_New_RegisteredPolicy(t *testing.T) {
+	const custom policies.PolicyType = "custom-lru"
+	err := policies.Register(custom, func(cfg policies.Config[int]) policies.Policy[int] {
+		return policies.NewLRU[int]()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { policies.Unregister[int](custom) })
+
+	c, err := NewCache[int, int](NewOptions[int]().SetPolicy(custom))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ptype, _ := c.shards[0].Policy.Type(); ptype != policies.TypeLRU {
+		t.Errorf("expected LRU, got %s", ptype)
+	}
+
+	// registered for int keys only
+	_, err = NewCache[string, int](NewOptions[string]().SetPolicy(custom))
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestCache_New_PolicyPerShard(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetPolicy(policies.TypeLRU))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 1; i < len(c.shards); i++ {
+		if c.shards[i].Policy == c.shards[0].Policy {
+			t.Fatalf("shard %d shares its policy with shard 0", i)
+		}
+	}
+}
+
 func TestCache_SetPolicy(t *testing.T) {
 	c, _ := NewCache[int, int](NewOptions[int]())
-	err := c.SetPolicy(policies.NewLRU[int]())
+	err := c.SetPolicy(func() policies.Policy[int] { return policies.NewLRU[int]() })
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	for i := 1; i < len(c.shards); i++ {
+		if c.shards[i].Policy == c.shards[0].Policy {
+			t.Fatalf("shard %d shares its policy with shard 0", i)
+		}
+	}
+
+	err = c.SetPolicy(nil)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
 
 	c.Set(1, 1)
 
-	err = c.SetPolicy(policies.NewFIFO[int]())
+	err = c.SetPolicy(func() policies.Policy[int] { return policies.NewFIFO[int]() })
 	if err == nil {
 		t.Errorf("expected error, got nil")
 	}
+	// no shard changed, not even the empty ones
+	for i, s := range c.shards {
+		if ptype, _ := s.Policy.Type(); ptype != policies.TypeLRU {
+			t.Errorf("shard %d: expected LRU, got %v", i, ptype)
+		}
+	}
+}
+
+// Policies are swapped under the shard locks, run with -race.
+func TestCache_SetPolicy_Concurrent(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetJanitorInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 1000 {
+			c.Get(i)
+			c.Peek(i)
+		}
+	}()
+	for range 100 {
+		if err := c.SetPolicy(func() policies.Policy[int] { return policies.NewLRU[int]() }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	<-done
 }
 
 func TestCache_Set(t *testing.T) {
@@ -407,3 +567,319 @@ func TestCache_Stats(t *testing.T) {
 		t.Errorf("expected stats.len=0, got %d", c.Len())
 	}
 }
+
+func TestCache_SetWithCost(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetCapacity(0).
+		SetMaxCost(20).
+		SetNumShards(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 10 per shard
+	if ok, _ := c.SetWithCost(1, 1, 11); ok {
+		t.Errorf("expected failure, got true")
+	}
+	total_evicted := 0
+	for i := range 10 {
+		ok, evicted := c.SetWithCost(i, i, 4)
+		if !ok {
+			t.Fatalf("expected success, got false")
+		}
+		total_evicted += evicted
+	}
+
+	stats := c.Stats()
+	if stats.Evictions != uint64(total_evicted) {
+		t.Errorf("expected evictions=%d, got %d", total_evicted, stats.Evictions)
+	}
+	if len(stats.ShardCost) != 2 {
+		t.Fatalf("expected 2 shard costs, got %d", len(stats.ShardCost))
+	}
+	for i, cost := range stats.ShardCost {
+		if cost > 10 {
+			t.Errorf("shard %d: expected cost <= 10, got %d", i, cost)
+		}
+	}
+	if want := int64(4 * c.Len()); stats.Cost != want {
+		t.Errorf("expected cost=%d, got %d", want, stats.Cost)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+// W-TinyLFU takes every new key into its window, also on a full cache.
+func TestCache_WTinyLFU_Full(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetCapacity(100).
+		SetNumShards(1).
+		SetPolicy(policies.TypeWTinyLFU))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 10_000 {
+		if ok, _ := c.Set(i, i); !ok {
+			t.Fatalf("expected key=%d to be set", i)
+		}
+	}
+	success, _ := c.SetMany(map[int]int{-1: 1, -2: 2})
+	if !success[-1] || !success[-2] {
+		t.Errorf("expected both keys set, got %v", success)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+// CLOCK-Pro with one key per shard, and without a capacity.
+func TestCache_CLOCKPro_SmallShards(t *testing.T) {
+	for _, opts := range []*Options[int]{
+		NewOptions[int]().SetCapacity(16).SetNumShards(16),
+		NewOptions[int]().SetCapacity(0).SetMaxCost(64).SetNumShards(16),
+	} {
+		c, err := NewCache[int, int](opts.SetPolicy(policies.TypeCLOCKPro))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r := rand.New(rand.NewSource(1))
+		for range 10_000 {
+			k := r.Intn(100)
+			if _, hit := c.Get(k); !hit {
+				c.Set(k, k)
+			}
+		}
+		if err := c.validate(); err != nil {
+			t.Errorf("cache not valid: %v", err)
+		}
+		c.Close()
+	}
+}
+
+func TestCache_SetWeigher(t *testing.T) {
+	c, err := NewCache[string, string](NewOptions[string]().
+		SetMaxCost(10).
+		SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWeigher(func(k, v string) int64 { return int64(len(v)) })
+
+	c.Set("a", "aaaa")
+	c.Set("b", "bbbb")
+	if cost := c.Stats().Cost; cost != 8 {
+		t.Fatalf("expected cost=8, got %d", cost)
+	}
+
+	// evicts "a" (FIFO) to make room
+	ok, evicted := c.SetWithTTL("c", "cccc", time.Minute)
+	if !ok || evicted != 1 {
+		t.Fatalf("expected (true, 1), got (%v, %d)", ok, evicted)
+	}
+	if _, ok := c.Peek("a"); ok {
+		t.Errorf("expected 'a' evicted")
+	}
+
+	c.Flush()
+	if cost := c.Stats().Cost; cost != 0 {
+		t.Errorf("expected cost=0, got %d", cost)
+	}
+}
+
+func TestCache_MaxMemory(t *testing.T) {
+	c, err := NewCache[int, []byte](NewOptions[int]().
+		SetCapacity(0).
+		SetMaxMemory(1 << 20).
+		SetNumShards(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range 100 {
+		c.Set(i, make([]byte, 32<<10))
+	}
+
+	stats := c.Stats()
+	if stats.Memory > 1<<20 {
+		t.Errorf("expected memory <= %d, got %d", 1<<20, stats.Memory)
+	}
+	if stats.Evictions == 0 {
+		t.Errorf("expected evictions, got 0")
+	}
+	var sum int64
+	for _, m := range stats.ShardMemory {
+		sum += m
+	}
+	if sum != stats.Memory {
+		t.Errorf("expected sum(shard memory)=%d, got %d", stats.Memory, sum)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+func TestCache_SetOnEvict_SetOnRemove(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetCapacity(2).
+		SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var evicted, removed []RemovalReason
+	c.SetOnEvict(func(k, v int, reason RemovalReason) {
+		evicted = append(evicted, reason)
+	})
+	c.SetOnRemove(func(k, v int, reason RemovalReason) {
+		removed = append(removed, reason)
+		c.Peek(k) // callbacks may use the cache
+	})
+
+	c.Set(1, 1)
+	c.Set(1, 2)
+	c.Set(2, 2)
+	c.Set(3, 3)
+	c.SetWithTTL(4, 4, -time.Second)
+	c.Get(4)
+	c.Del(3)
+	c.Flush()
+
+	wantEvicted := []RemovalReason{ReasonEvicted, ReasonEvicted, ReasonExpired}
+	wantRemoved := []RemovalReason{
+		ReasonReplaced, ReasonEvicted, ReasonEvicted, ReasonExpired, ReasonDeleted,
+	}
+	if !slices.Equal(evicted, wantEvicted) {
+		t.Errorf("expected %v, got %v", wantEvicted, evicted)
+	}
+	if !slices.Equal(removed, wantRemoved) {
+		t.Errorf("expected %v, got %v", wantRemoved, removed)
+	}
+
+	// nil disables the callback
+	c.SetOnRemove(nil)
+	c.Set(5, 5)
+	c.Del(5)
+	if len(removed) != len(wantRemoved) {
+		t.Errorf("expected no more removals, got %v", removed)
+	}
+}
+
+func TestNewCache_ExpiryIndex(t *testing.T) {
+	if _, err := NewCache[int, int](NewOptions[int]().SetExpiryIndex("unknown")); err == nil {
+		t.Errorf("expected error for an unknown expiry index")
+	}
+
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetExpiryIndex(ttl_queue.TypeTimingWheel).
+		SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWithTTL(1, 1, time.Millisecond)
+	c.SetWithTTL(2, 2, time.Minute)
+	time.Sleep(2*time.Millisecond + ttl_queue.DefaultTick)
+
+	c.Get(2) // hit: removes expired entries
+	if l := c.Len(); l != 1 {
+		t.Errorf("expected len=1, got %d", l)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+func TestNewCache_NoExpiryIndex(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetExpiryIndex(ttl_queue.TypeNone))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWithTTL(1, 1, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if l := c.Len(); l != 1 {
+		t.Errorf("expected expired key to stay until read, got len=%d", l)
+	}
+	if _, hit := c.Get(1); hit {
+		t.Errorf("expected miss for an expired key")
+	}
+	if l := c.Len(); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+
+	// still validated without a default TTL
+	if _, err := NewCache[int, int](NewOptions[int]().SetDefaultTTL(0).SetExpiryIndex("unknown")); err == nil {
+		t.Errorf("expected error for an unknown expiry index")
+	}
+}
+
+func TestCache_Janitor(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetNumShards(8).
+		SetJanitorInterval(5 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 100 {
+		c.SetWithTTL(i, i, time.Millisecond)
+	}
+	c.Set(100, 100)
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Janitor.Expired < 100 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 100 expired entries, got %+v", c.Stats().Janitor)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if l := c.Len(); l != 1 {
+		t.Errorf("expected len=1, got %d", l)
+	}
+	if stats := c.Stats().Janitor; stats.Sweeps == 0 || stats.MaxLockHeld <= 0 {
+		t.Errorf("expected sweeps to be recorded, got %+v", stats)
+	}
+
+	// no janitor without a default TTL
+	c, err = NewCache[int, int](NewOptions[int]().SetDefaultTTL(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.janitor != nil {
+		t.Errorf("expected no janitor")
+	}
+	if stats := c.Stats().Janitor; stats != (core.JanitorStats{}) {
+		t.Errorf("expected empty janitor stats, got %+v", stats)
+	}
+}
+
+// Incremental sweeps find expired entries by sampling when they aren't
+// indexed.
+func TestCache_Janitor_Incremental(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetNumShards(4).
+		SetExpiryIndex(ttl_queue.TypeNone).
+		SetJanitorInterval(5 * time.Millisecond).
+		SetJanitorSweepMode(SweepIncremental))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 200 {
+		c.SetWithTTL(i, i, time.Millisecond)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Janitor.Expired < 200 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected expired entries to be removed without reads, got %+v", c.Stats().Janitor)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if stats := c.Stats().Janitor; stats.Batches <= stats.Sweeps {
+		t.Errorf("expected several batches per sweep, got %+v", stats)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l := c.Len(); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by operations that report errors once the cache is
+// closed. Other operations behave as if the cache is empty and rejects
+// writes: gets miss and sets, deletes and updates fail.
+var ErrClosed = errors.New("cache closed")
+
+// Close is Shutdown without a deadline.
+func (c *Cache[K, V]) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown closes the cache: it rejects further operations, stops the
+// janitor after a final sweep of expired entries and waits for background
+// refreshes to finish, so no callbacks run after it returns. Operations that
+// were already running when Shutdown was called may still complete.
+// If ctx is done first, background refreshes get their ctx cancelled and
+// ctx.Err() is returned, the shutdown itself continues.
+// Safe to call from multiple goroutines and more than once, every call
+// waits for the same shutdown. Don't call it from a callback (see
+// SetOnEvict), it would wait for the goroutine it runs on.
+func (c *Cache[K, V]) Shutdown(ctx context.Context) error {
+	c.lifecycle.Lock()
+	if !c.closing.Load() {
+		c.closing.Store(true)
+		go c.shutdown()
+	}
+	c.lifecycle.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		c.cancelLoads()
+		return ctx.Err()
+	}
+}
+
+func (c *Cache[K, V]) shutdown() {
+	if c.janitor != nil {
+		c.janitor.Stop()
+	}
+	c.loads.Wait()
+	c.cancelLoads()
+	close(c.done)
+}
+
+// closed reports whether Close or Shutdown was called.
+func (c *Cache[K, V]) closed() bool {
+	return c.closing.Load()
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_Close(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set(1, 1)
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ok, _ := c.Set(2, 2); ok {
+		t.Errorf("expected set to fail after close")
+	}
+	if _, hit := c.Get(1); hit {
+		t.Errorf("expected miss after close")
+	}
+	if c.Del(1) {
+		t.Errorf("expected del to fail after close")
+	}
+	if v, ok := Incr(c, 1, 1); ok {
+		t.Errorf("expected incr to fail after close, got %d", v)
+	}
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		t.Errorf("loader called after close")
+		return k, 0, nil
+	}
+	if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	batchLoader := func(ctx context.Context, keys []int) (map[int]int, error) {
+		t.Errorf("batch loader called after close")
+		return nil, nil
+	}
+	if _, err := c.GetManyOrLoad(context.Background(), []int{1, 2}, batchLoader); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := c.SetIfVersion(1, 1, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	// idempotent
+	if err := c.Close(); err != nil {
+		t.Errorf("unexpected error on second close: %v", err)
+	}
+}
+
+// Close sweeps expired entries one last time, their callbacks have run when
+// it returns.
+func TestCache_Close_FinalSweep(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetJanitorInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var expired []int
+	c.SetOnEvict(func(k, v int, reason RemovalReason) {
+		if reason == ReasonExpired {
+			expired = append(expired, k)
+		}
+	})
+	c.SetWithTTL(1, 1, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != 1 {
+		t.Errorf("expected key=1 expired, got %v", expired)
+	}
+}
+
+func TestCache_Shutdown_Refresh(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetDefaultTTL(time.Minute).
+		SetRefreshAfter(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	c.SetLoader(func(ctx context.Context, k int) (int, time.Duration, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, 0, ctx.Err()
+	})
+	c.Set(1, 1)
+	time.Sleep(2 * time.Millisecond)
+	c.Get(1) // starts a refresh
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected refresh to be cancelled")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCache_Close_Concurrent(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Set(i, i)
+			c.Get(i)
+			if err := c.Close(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCache_Close_NoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 20 {
+		c, err := NewCache[int, int](NewOptions[int]().
+			SetNumShards(16).
+			SetRefreshAfter(time.Millisecond))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c.SetLoader(func(ctx context.Context, k int) (int, time.Duration, error) {
+			return k, 0, nil
+		})
+		for i := range 100 {
+			c.Set(i, i)
+		}
+		time.Sleep(2 * time.Millisecond)
+		for i := range 100 {
+			c.Get(i) // refreshes in the background
+		}
+		c.Flush()
+		if err := c.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the shutdown goroutine may still be exiting
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d goroutines, got %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
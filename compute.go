@@ -0,0 +1,89 @@
+package cache
+
+import "github.com/jeltjongsma/go-cache/internal/core"
+
+// ComputeOp tells Compute what to do with the value returned by its func.
+type ComputeOp = core.ComputeOp
+
+const (
+	ComputeUpdate = core.ComputeUpdate // store the new value
+	ComputeDelete = core.ComputeDelete // remove the key
+	ComputeKeep   = core.ComputeKeep   // leave the cache unchanged
+)
+
+// GetOrSet returns the value of key if present, otherwise it stores val.
+// loaded reports whether the value was present.
+func (c *Cache[K, V]) GetOrSet(key K, val V) (actual V, loaded bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	actual, loaded, evicted := shard.GetOrSet(key, val)
+	c.stats.Evictions.Add(uint64(evicted))
+	if loaded {
+		c.stats.Hits.Add(1)
+	} else {
+		c.stats.Misses.Add(1)
+	}
+	return actual, loaded
+}
+
+// Compute calls fn with the current value of key (exists is false on a
+// miss) and updates, deletes or keeps the key depending on the returned op.
+// fn runs under the shard lock, so it must not use the cache.
+// Updated values get the default TTL, like Set. Returns the value of key
+// afterwards and whether it is present.
+func (c *Cache[K, V]) Compute(key K, fn func(old V, exists bool) (V, ComputeOp)) (val V, present bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	var deleted bool
+	val, present, evicted := shard.Compute(key, func(old V, exists bool) (V, ComputeOp) {
+		v, op := fn(old, exists)
+		deleted = exists && op == ComputeDelete
+		return v, op
+	})
+	c.stats.Evictions.Add(uint64(evicted))
+	if deleted {
+		c.stats.Deletes.Add(1)
+	}
+	return val, present
+}
+
+// CompareAndSwap stores new when the current value of key equals old
+// according to eq, which runs under the shard lock.
+func (c *Cache[K, V]) CompareAndSwap(key K, old, new V, eq func(a, b V) bool) (swapped bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	swapped, evicted := shard.CompareAndSwap(key, old, new, eq)
+	c.stats.Evictions.Add(uint64(evicted))
+	return swapped
+}
+
+// CompareAndDelete deletes key when its current value equals old according
+// to eq, which runs under the shard lock.
+func (c *Cache[K, V]) CompareAndDelete(key K, old V, eq func(a, b V) bool) (deleted bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	if deleted = shard.CompareAndDelete(key, old, eq); deleted {
+		c.stats.Deletes.Add(1)
+	}
+	return deleted
+}
+
+// Swap stores val and returns the previous value, loaded reports whether
+// there was one.
+func (c *Cache[K, V]) Swap(key K, val V) (previous V, loaded bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	previous, loaded, evicted := shard.Swap(key, val)
+	c.stats.Evictions.Add(uint64(evicted))
+	return previous, loaded
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCache_GetOrSet(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual, loaded := c.GetOrSet("a", 1); actual != 1 || loaded {
+		t.Errorf("expected (1, false), got (%d, %v)", actual, loaded)
+	}
+	if actual, loaded := c.GetOrSet("a", 2); actual != 1 || !loaded {
+		t.Errorf("expected (1, true), got (%d, %v)", actual, loaded)
+	}
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestCache_Compute_Concurrent(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const goroutines, iterations = 8, 1000
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				c.Compute("counter", func(old int, exists bool) (int, ComputeOp) {
+					return old + 1, ComputeUpdate
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get("counter"); v != goroutines*iterations {
+		t.Errorf("expected %d, got %d", goroutines*iterations, v)
+	}
+}
+
+func TestCache_Compute_Delete(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set("a", 1)
+	_, present := c.Compute("a", func(old int, exists bool) (int, ComputeOp) {
+		return 0, ComputeDelete
+	})
+	if present {
+		t.Errorf("expected present=false, got true")
+	}
+	if stats := c.Stats(); stats.Deletes != 1 {
+		t.Errorf("expected deletes=1, got %d", stats.Deletes)
+	}
+}
+
+func TestCache_CompareAndSwap_CompareAndDelete(t *testing.T) {
+	c, err := NewCache[string, []int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eq := func(a, b []int) bool { return len(a) == len(b) }
+	c.Set("a", []int{1})
+
+	if c.CompareAndSwap("a", []int{1, 2}, []int{3}, eq) {
+		t.Errorf("expected swapped=false, got true")
+	}
+	if !c.CompareAndSwap("a", []int{9}, []int{3, 4}, eq) {
+		t.Errorf("expected swapped=true, got false")
+	}
+	if c.CompareAndDelete("a", []int{1}, eq) {
+		t.Errorf("expected deleted=false, got true")
+	}
+	if !c.CompareAndDelete("a", []int{0, 0}, eq) {
+		t.Errorf("expected deleted=true, got false")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected len=0, got %d", c.Len())
+	}
+}
+
+func TestCache_Swap(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prev, loaded := c.Swap("a", 1); prev != 0 || loaded {
+		t.Errorf("expected (0, false), got (%d, %v)", prev, loaded)
+	}
+	if prev, loaded := c.Swap("a", 2); prev != 1 || !loaded {
+		t.Errorf("expected (1, true), got (%d, %v)", prev, loaded)
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import "time"
+
+// Number is the constraint for the value type of caches used as counters,
+// see Incr and Decr.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Incr atomically adds delta to the value of key and returns the result.
+// The expiry of key is kept. Like memcached's incr it fails on a miss,
+// integers wrap around on overflow.
+func Incr[K comparable, V Number](c *Cache[K, V], key K, delta V) (V, bool) {
+	return c.update(key, func(old V) V { return old + delta })
+}
+
+// Decr atomically subtracts delta from the value of key and returns the
+// result. The expiry of key is kept. Like memcached's decr it fails on a
+// miss and a value of 0 or more never drops below 0, so unsigned counters
+// don't wrap around. Negative values are decremented as usual.
+func Decr[K comparable, V Number](c *Cache[K, V], key K, delta V) (V, bool) {
+	return c.update(key, func(old V) V { return decr(old, delta) })
+}
+
+// IncrOrInit is like Incr, but stores initial with ttl when key is missing
+// and returns it. delta isn't applied to initial.
+func IncrOrInit[K comparable, V Number](c *Cache[K, V], key K, delta, initial V, ttl time.Duration) (V, bool) {
+	return c.updateOrSet(key, func(old V) V { return old + delta }, initial, ttl)
+}
+
+// DecrOrInit is like Decr, but stores initial with ttl when key is missing
+// and returns it. delta isn't applied to initial.
+func DecrOrInit[K comparable, V Number](c *Cache[K, V], key K, delta, initial V, ttl time.Duration) (V, bool) {
+	return c.updateOrSet(key, func(old V) V { return decr(old, delta) }, initial, ttl)
+}
+
+// decr clamps at 0 only when old would cross it.
+func decr[V Number](old, delta V) V {
+	if delta > 0 && old >= 0 && old < delta {
+		return 0
+	}
+	return old - delta
+}
+
+func (c *Cache[K, V]) update(key K, fn func(old V) V) (V, bool) {
+	if c.closed() {
+		var zero V
+		return zero, false
+	}
+	shard, _ := c.shardFor(key)
+	val, ok, evicted := shard.Update(key, fn)
+	c.stats.Evictions.Add(uint64(evicted))
+	return val, ok
+}
+
+func (c *Cache[K, V]) updateOrSet(key K, fn func(old V) V, init V, ttl time.Duration) (V, bool) {
+	if c.closed() {
+		var zero V
+		return zero, false
+	}
+	shard, _ := c.shardFor(key)
+	val, ok, evicted := shard.UpdateOrSet(key, fn, init, ttl)
+	c.stats.Evictions.Add(uint64(evicted))
+	return val, ok
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncr(t *testing.T) {
+	c, err := NewCache[string, int64](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := Incr(c, "a", 1); ok {
+		t.Errorf("expected ok=false on a miss, got true")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected len=0, got %d", c.Len())
+	}
+
+	c.Set("a", 1)
+	if v, ok := Incr(c, "a", 2); v != 3 || !ok {
+		t.Errorf("expected (3, true), got (%d, %v)", v, ok)
+	}
+	if v, _ := c.Get("a"); v != 3 {
+		t.Errorf("expected 3, got %d", v)
+	}
+}
+
+// An Incr that evicts its own key to fit the new cost keeps the key, its
+// expiry and its index entry.
+func TestIncr_MaxCost(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetCapacity(0).
+		SetMaxCost(10).
+		SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWeigher(func(k, v int) int64 { return int64(v) })
+	var evicted []int
+	c.SetOnEvict(func(k, v int, reason RemovalReason) {
+		evicted = append(evicted, k)
+	})
+
+	c.Set(1, 2)
+	c.Set(2, 2)
+	if v, ok := Incr(c, 1, 5); v != 7 || !ok {
+		t.Fatalf("expected (7, true), got (%d, %v)", v, ok)
+	}
+	// FIFO picks 1 first, then 2
+	if v, ok := Incr(c, 1, 2); v != 9 || !ok {
+		t.Fatalf("expected (9, true), got (%d, %v)", v, ok)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+	if ttl, ok := c.TTL(1); !ok || ttl <= 0 || ttl > 5*time.Minute {
+		t.Errorf("expected a ttl in (0, 5m], got (%v, %v)", ttl, ok)
+	}
+	if len(evicted) != 1 || evicted[0] != 2 {
+		t.Errorf("expected key=2 evicted, got %v", evicted)
+	}
+}
+
+func TestDecr(t *testing.T) {
+	c, err := NewCache[string, uint64](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := Decr(c, "a", 1); ok {
+		t.Errorf("expected ok=false on a miss, got true")
+	}
+
+	c.Set("a", 5)
+	if v, ok := Decr(c, "a", 2); v != 3 || !ok {
+		t.Errorf("expected (3, true), got (%d, %v)", v, ok)
+	}
+	// never below 0
+	if v, ok := Decr(c, "a", 10); v != 0 || !ok {
+		t.Errorf("expected (0, true), got (%d, %v)", v, ok)
+	}
+}
+
+func TestDecr_Negative(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set("a", 3)
+	if v, ok := Decr(c, "a", 5); v != 0 || !ok {
+		t.Errorf("expected (0, true), got (%d, %v)", v, ok)
+	}
+	// negative counters aren't clamped
+	c.Set("b", -5)
+	if v, ok := Decr(c, "b", 1); v != -6 || !ok {
+		t.Errorf("expected (-6, true), got (%d, %v)", v, ok)
+	}
+	if v, ok := Decr(c, "b", -10); v != 4 || !ok {
+		t.Errorf("expected (4, true), got (%d, %v)", v, ok)
+	}
+}
+
+func TestIncrOrInit_DecrOrInit(t *testing.T) {
+	c, err := NewCache[string, float64](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok := IncrOrInit(c, "a", 0.5, 10, time.Minute); v != 10 || !ok {
+		t.Errorf("expected (10, true), got (%v, %v)", v, ok)
+	}
+	if v, ok := IncrOrInit(c, "a", 0.5, 10, time.Minute); v != 10.5 || !ok {
+		t.Errorf("expected (10.5, true), got (%v, %v)", v, ok)
+	}
+	if v, ok := DecrOrInit(c, "b", 1, 3, time.Minute); v != 3 || !ok {
+		t.Errorf("expected (3, true), got (%v, %v)", v, ok)
+	}
+	if v, ok := DecrOrInit(c, "b", 1, 3, time.Minute); v != 2 || !ok {
+		t.Errorf("expected (2, true), got (%v, %v)", v, ok)
+	}
+}
+
+func TestIncr_KeepsTTL(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	IncrOrInit(c, "a", 1, 1, 50*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	Incr(c, "a", 1)
+	time.Sleep(30 * time.Millisecond)
+
+	if _, ok := Incr(c, "a", 1); ok {
+		t.Errorf("expected key to expire at its original ttl")
+	}
+}
+
+func TestIncr_Concurrent(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const goroutines, iterations = 8, 1000
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				IncrOrInit(c, "hits", 1, 1, time.Minute)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get("hits"); v != goroutines*iterations {
+		t.Errorf("expected %d, got %d", goroutines*iterations, v)
+	}
+}
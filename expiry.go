@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/jeltjongsma/go-cache/internal/core"
+)
+
+// SetWithSlidingTTL is like SetWithTTL, but ttl is an idle timeout: every
+// Get (or other read that counts as a hit) extends the expiry by ttl again.
+// Peek doesn't.
+func (c *Cache[K, V]) SetWithSlidingTTL(key K, val V, ttl time.Duration) (success bool, evicted int) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	success, evicted = shard.SetWithSlidingTTL(key, val, ttl)
+	c.stats.Evictions.Add(uint64(evicted))
+	return
+}
+
+// Touch lets key expire ttl from now, without rewriting the value or
+// counting as a hit for the eviction policy. For entries set with
+// SetWithSlidingTTL, ttl becomes the new idle timeout.
+// Returns false if key is missing or expired, or when the cache has no
+// DefaultTTL: entries don't expire then and the call would change nothing.
+func (c *Cache[K, V]) Touch(key K, ttl time.Duration) bool {
+	if c.closed() {
+		return false
+	}
+	shard, _ := c.shardFor(key)
+	return shard.Touch(key, ttl)
+}
+
+// Expire lets key expire at the given time, entries set with
+// SetWithSlidingTTL stop sliding. Returns false if key is missing or
+// expired, or when the cache has no DefaultTTL.
+func (c *Cache[K, V]) Expire(key K, at time.Time) bool {
+	if c.closed() {
+		return false
+	}
+	shard, _ := c.shardFor(key)
+	return shard.Expire(key, at)
+}
+
+// NoExpiry is returned by TTL for keys that don't expire.
+const NoExpiry = core.NoExpiry
+
+// GetWithExpiry is like Get, but also returns when the entry expires, the
+// zero time if it doesn't. Entries served from the stale window (see
+// SetStaleTTL) return an expiry in the past.
+func (c *Cache[K, V]) GetWithExpiry(key K) (val V, expiresAt time.Time, hit bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	var refresh bool
+	val, expiresAt, hit, refresh = shard.GetWithExpiry(key)
+	if refresh && c.loader != nil {
+		c.refresh(key)
+	}
+	if hit {
+		c.stats.Hits.Add(1)
+	} else {
+		c.stats.Misses.Add(1)
+	}
+	return
+}
+
+// TTL returns the remaining lifetime of key, or NoExpiry if it doesn't
+// expire, like Redis' TTL. It doesn't affect the eviction policy or stats.
+// Returns false if key is missing or expired.
+func (c *Cache[K, V]) TTL(key K) (time.Duration, bool) {
+	if c.closed() {
+		return 0, false
+	}
+	shard, _ := c.shardFor(key)
+	return shard.TTL(key)
+}
+
+// Persist removes the expiry of key, like Redis' PERSIST. Set, Touch and
+// Expire give it an expiry again. Returns false if key is missing, expired
+// or has no expiry.
+func (c *Cache[K, V]) Persist(key K) bool {
+	if c.closed() {
+		return false
+	}
+	shard, _ := c.shardFor(key)
+	return shard.Persist(key)
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_SetWithSlidingTTL(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWithSlidingTTL("a", 1, 50*time.Millisecond)
+	for range 4 {
+		time.Sleep(25 * time.Millisecond)
+		if _, hit := c.Get("a"); !hit {
+			t.Fatalf("expected hit, sliding entry expired")
+		}
+	}
+	time.Sleep(75 * time.Millisecond)
+	if _, hit := c.Get("a"); hit {
+		t.Errorf("expected miss after idle timeout")
+	}
+}
+
+func TestCache_Touch_Expire(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Touch("a", time.Minute) || c.Expire("a", time.Now()) {
+		t.Errorf("expected false on a miss")
+	}
+
+	c.SetWithTTL("a", 1, 20*time.Millisecond)
+	if !c.Touch("a", time.Minute) {
+		t.Errorf("expected true on a hit")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, hit := c.Get("a"); !hit {
+		t.Errorf("expected Touch to extend the ttl")
+	}
+
+	if !c.Expire("a", time.Now().Add(-time.Millisecond)) {
+		t.Errorf("expected true on a hit")
+	}
+	if _, hit := c.Get("a"); hit {
+		t.Errorf("expected miss after Expire")
+	}
+}
+
+func TestCache_TTL_Persist(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.TTL("a"); ok {
+		t.Errorf("expected false on a miss")
+	}
+	c.SetWithTTL("a", 1, time.Minute)
+	if ttl, ok := c.TTL("a"); ttl <= 0 || ttl > time.Minute || !ok {
+		t.Errorf("expected (0 < ttl <= 1m, true), got (%v, %v)", ttl, ok)
+	}
+	if stats := c.Stats(); stats.Hits != 0 {
+		t.Errorf("expected TTL not to count as a hit")
+	}
+
+	if !c.Persist("a") {
+		t.Errorf("expected true on a hit")
+	}
+	if ttl, ok := c.TTL("a"); ttl != NoExpiry || !ok {
+		t.Errorf("expected (NoExpiry, true), got (%v, %v)", ttl, ok)
+	}
+	if _, at, hit := c.GetWithExpiry("a"); !at.IsZero() || !hit {
+		t.Errorf("expected (zero time, true), got (%v, %v)", at, hit)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("cache not valid: %v", err)
+	}
+}
+
+func TestCache_GetWithExpiry(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := time.Now()
+	c.SetWithTTL("a", 1, time.Minute)
+	val, at, hit := c.GetWithExpiry("a")
+	if val != 1 || !hit {
+		t.Errorf("expected (1, true), got (%d, %v)", val, hit)
+	}
+	if at.Before(before.Add(time.Minute)) || at.After(time.Now().Add(time.Minute)) {
+		t.Errorf("expected expiry about 1m from now, got %v", time.Until(at))
+	}
+	if stats := c.Stats(); stats.Hits != 1 {
+		t.Errorf("expected hits=1, got %d", stats.Hits)
+	}
+}
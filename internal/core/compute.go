@@ -0,0 +1,170 @@
+package core
+
+import "time"
+
+// ComputeOp tells Compute what to do with the value returned by its func.
+type ComputeOp uint8
+
+const (
+	ComputeUpdate ComputeOp = iota // store the new value
+	ComputeDelete                  // remove the key
+	ComputeKeep                    // leave the cache unchanged
+)
+
+// live returns the entry of key unless it expired, expired entries are
+// removed. Unlike Get it doesn't affect the policy. Must hold the lock.
+func (s *Shard[K, V]) live(key K, now time.Time) (Entry[V], bool) {
+	entry, ok := s.Store[key]
+	if !ok {
+		return entry, false
+	}
+	if s.expired(entry, now) {
+		s.drop(key, ReasonExpired)
+		s.Policy.OnDel(key)
+		s.expiry.Cancel(key)
+		return Entry[V]{}, false
+	}
+	return entry, true
+}
+
+// hit records a read of key for the policy and extends a sliding expiry,
+// must hold the lock.
+func (s *Shard[K, V]) hit(key K, entry Entry[V], now time.Time) {
+	if s.slide(key, &entry, now) {
+		s.Store[key] = entry
+	}
+	s.touch(key)
+	s.Policy.OnHit(key)
+}
+
+// remove deletes key, must hold the lock.
+func (s *Shard[K, V]) remove(key K) bool {
+	if _, ok := s.drop(key, ReasonDeleted); !ok {
+		return false
+	}
+	s.Policy.OnDel(key)
+	s.expiry.Cancel(key)
+	return true
+}
+
+// GetOrSet returns the value of key if present, otherwise it stores val with
+// the default TTL. loaded reports whether the value was present.
+func (s *Shard[K, V]) GetOrSet(key K, val V) (actual V, loaded bool, evicted int) {
+	size := s.size(key, val)
+	s.mu.Lock()
+	defer s.unlock()
+
+	now := s.now()
+	if entry, ok := s.live(key, now); ok {
+		s.hit(key, entry, now)
+		return entry.val, true, 0
+	}
+	_, evicted = s.put(key, val, size, s.defaultTTL)
+	return val, false, evicted
+}
+
+// Compute calls fn with the current value of key and applies the returned
+// op atomically. An updated value gets the default TTL, like Set.
+// Returns the value of key afterwards and whether it is present.
+func (s *Shard[K, V]) Compute(key K, fn func(old V, exists bool) (V, ComputeOp)) (val V, present bool, evicted int) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, exists := s.live(key, s.now())
+	val, op := fn(entry.val, exists)
+	switch op {
+	case ComputeUpdate:
+		present, evicted = s.put(key, val, s.measure(key, val), s.defaultTTL)
+		if !present {
+			// rejected, the old value is left in place
+			entry, present = s.Store[key]
+			return entry.val, present, evicted
+		}
+		return val, true, evicted
+	case ComputeDelete:
+		s.remove(key)
+		var zero V
+		return zero, false, 0
+	default:
+		return entry.val, exists, 0
+	}
+}
+
+// CompareAndSwap stores new when the current value of key equals old
+// according to eq. The new value gets the default TTL, like Set.
+func (s *Shard[K, V]) CompareAndSwap(key K, old, new V, eq func(a, b V) bool) (swapped bool, evicted int) {
+	size := s.size(key, new)
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, ok := s.live(key, s.now())
+	if !ok || !eq(entry.val, old) {
+		return false, 0
+	}
+	return s.put(key, new, size, s.defaultTTL)
+}
+
+// CompareAndDelete deletes key when its current value equals old according
+// to eq.
+func (s *Shard[K, V]) CompareAndDelete(key K, old V, eq func(a, b V) bool) (deleted bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, ok := s.live(key, s.now())
+	if !ok || !eq(entry.val, old) {
+		return false
+	}
+	return s.remove(key)
+}
+
+// Swap stores val with the default TTL and returns the previous value.
+func (s *Shard[K, V]) Swap(key K, val V) (previous V, loaded bool, evicted int) {
+	size := s.size(key, val)
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, loaded := s.live(key, s.now())
+	_, evicted = s.put(key, val, size, s.defaultTTL)
+	return entry.val, loaded, evicted
+}
+
+// Update replaces the value of key with fn(old) and keeps its expiry.
+// Returns false without calling fn when key is missing, or when the new
+// value was rejected.
+func (s *Shard[K, V]) Update(key K, fn func(old V) V) (val V, ok bool, evicted int) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, ok := s.live(key, s.now())
+	if !ok {
+		return val, false, 0
+	}
+	return s.update(key, entry, fn)
+}
+
+// UpdateOrSet is like Update, but stores init with ttl when key is missing.
+func (s *Shard[K, V]) UpdateOrSet(key K, fn func(old V) V, init V, ttl time.Duration) (val V, ok bool, evicted int) {
+	size := s.size(key, init)
+	s.mu.Lock()
+	defer s.unlock()
+
+	if entry, ok := s.live(key, s.now()); ok {
+		return s.update(key, entry, fn)
+	}
+	ok, evicted = s.put(key, init, size, ttl)
+	return init, ok, evicted
+}
+
+// update stores fn(entry.val) under key and keeps its expiry, must hold the
+// lock.
+func (s *Shard[K, V]) update(key K, entry Entry[V], fn func(old V) V) (val V, ok bool, evicted int) {
+	val = fn(entry.val)
+	entry.val = val
+	entry.cost = s.weigh(key, val)
+	entry.size = s.withOverhead(s.measure(key, val))
+	if ok, evicted = s.store(key, entry); !ok {
+		var zero V
+		return zero, false, evicted
+	}
+	return val, true, evicted
+}
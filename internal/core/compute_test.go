@@ -0,0 +1,211 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/policies"
+)
+
+func eqInt(a, b int) bool { return a == b }
+
+func TestShard_GetOrSet(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, 100)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	actual, loaded, _ := s.GetOrSet(1, 10)
+	if actual != 10 || loaded {
+		t.Errorf("expected (10, false), got (%d, %v)", actual, loaded)
+	}
+	actual, loaded, _ = s.GetOrSet(1, 20)
+	if actual != 10 || !loaded {
+		t.Errorf("expected (10, true), got (%d, %v)", actual, loaded)
+	}
+
+	// expired values count as missing
+	s.SetWithTTL(2, 2, -50)
+	if actual, loaded, _ = s.GetOrSet(2, 20); actual != 20 || loaded {
+		t.Errorf("expected (20, false), got (%d, %v)", actual, loaded)
+	}
+	if e := s.Store[2]; !e.expiry().Equal(n.Add(100)) {
+		t.Errorf("expected default ttl, got %v", e.expiry().Sub(n))
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_Compute(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	incr := func(old int, exists bool) (int, ComputeOp) {
+		return old + 1, ComputeUpdate
+	}
+
+	s.Compute(1, incr)
+	val, present, _ := s.Compute(1, incr)
+	if val != 2 || !present {
+		t.Errorf("expected (2, true), got (%d, %v)", val, present)
+	}
+
+	val, present, _ = s.Compute(1, func(old int, exists bool) (int, ComputeOp) {
+		return 100, ComputeKeep
+	})
+	if val != 2 || !present {
+		t.Errorf("expected (2, true), got (%d, %v)", val, present)
+	}
+
+	val, present, _ = s.Compute(1, func(old int, exists bool) (int, ComputeOp) {
+		return 0, ComputeDelete
+	})
+	if present {
+		t.Errorf("expected present=false, got true")
+	}
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 deleted")
+	}
+
+	// keep on a missing key
+	_, present, _ = s.Compute(2, func(old int, exists bool) (int, ComputeOp) {
+		if exists {
+			t.Errorf("expected exists=false, got true")
+		}
+		return 0, ComputeKeep
+	})
+	if present {
+		t.Errorf("expected present=false, got true")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_CompareAndSwap(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	s.Set(1, 1)
+
+	if ok, _ := s.CompareAndSwap(1, 2, 3, eqInt); ok {
+		t.Errorf("expected swapped=false, got true")
+	}
+	if ok, _ := s.CompareAndSwap(1, 1, 3, eqInt); !ok {
+		t.Errorf("expected swapped=true, got false")
+	}
+	if v := s.Store[1].val; v != 3 {
+		t.Errorf("expected 3, got %d", v)
+	}
+	if ok, _ := s.CompareAndSwap(2, 0, 3, eqInt); ok {
+		t.Errorf("expected swapped=false for missing key, got true")
+	}
+}
+
+func TestShard_CompareAndDelete(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	s.Set(1, 1)
+
+	if s.CompareAndDelete(1, 2, eqInt) {
+		t.Errorf("expected deleted=false, got true")
+	}
+	if !s.CompareAndDelete(1, 1, eqInt) {
+		t.Errorf("expected deleted=true, got false")
+	}
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 deleted")
+	}
+	if s.expiry.Len() != 0 {
+		t.Errorf("expected expiry len=0, got %d", s.expiry.Len())
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_Swap(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+
+	prev, loaded, _ := s.Swap(1, 1)
+	if prev != 0 || loaded {
+		t.Errorf("expected (0, false), got (%d, %v)", prev, loaded)
+	}
+	prev, loaded, _ = s.Swap(1, 2)
+	if prev != 1 || !loaded {
+		t.Errorf("expected (1, true), got (%d, %v)", prev, loaded)
+	}
+	if v := s.Store[1].val; v != 2 {
+		t.Errorf("expected 2, got %d", v)
+	}
+}
+
+func TestShard_Update(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	incr := func(old int) int { return old + 1 }
+
+	if _, ok, _ := s.Update(1, incr); ok {
+		t.Errorf("expected ok=false on a miss, got true")
+	}
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 not to be created")
+	}
+
+	s.SetWithTTL(1, 5, time.Second)
+	expiresAt := s.Store[1].expiry()
+	n = n.Add(500 * time.Millisecond)
+	if val, ok, _ := s.Update(1, incr); val != 6 || !ok {
+		t.Errorf("expected (6, true), got (%d, %v)", val, ok)
+	}
+	if e := s.Store[1]; !e.expiry().Equal(expiresAt) {
+		t.Errorf("expected expiry to be kept, moved by %v", e.expiry().Sub(expiresAt))
+	}
+
+	// expired keys are missing
+	n = n.Add(time.Second)
+	if _, ok, _ := s.Update(1, incr); ok {
+		t.Errorf("expected ok=false on an expired key, got true")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_UpdateOrSet(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	incr := func(old int) int { return old + 1 }
+
+	if val, ok, _ := s.UpdateOrSet(1, incr, 10, time.Second); val != 10 || !ok {
+		t.Errorf("expected (10, true), got (%d, %v)", val, ok)
+	}
+	if e := s.Store[1]; !e.expiry().Equal(n.Add(time.Second)) {
+		t.Errorf("expected ttl=1s, got %v", e.expiry().Sub(n))
+	}
+	n = n.Add(500 * time.Millisecond)
+	if val, ok, _ := s.UpdateOrSet(1, incr, 10, time.Second); val != 11 || !ok {
+		t.Errorf("expected (11, true), got (%d, %v)", val, ok)
+	}
+	if e := s.Store[1]; !e.expiry().Equal(n.Add(500 * time.Millisecond)) {
+		t.Errorf("expected expiry to be kept, got %v", e.expiry().Sub(n))
+	}
+}
+
+func TestShard_Update_Weigher(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	s.SetMaxCost(10)
+	s.SetWeigher(func(k, v int) int64 { return int64(v) })
+	s.Set(1, 4)
+	s.Set(2, 4)
+
+	if val, ok, evicted := s.Update(2, func(old int) int { return old + 2 }); val != 6 || !ok || evicted != 0 {
+		t.Errorf("expected (6, true, 0), got (%d, %v, %d)", val, ok, evicted)
+	}
+	if _, ok, evicted := s.Update(2, func(old int) int { return old + 1 }); !ok || evicted != 1 {
+		t.Errorf("expected (true, 1), got (%v, %d)", ok, evicted)
+	}
+	if _, ok, _ := s.Update(2, func(old int) int { return 11 }); ok {
+		t.Errorf("expected ok=false for a value over budget, got true")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
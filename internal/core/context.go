@@ -2,6 +2,7 @@ package core
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 type Stats struct {
@@ -18,4 +19,19 @@ type StatsSnapshot struct {
 	Evictions uint64
 	Deletes   uint64
 	Flushes   uint64
+	Cost      int64   // total cost of all entries
+	ShardCost []int64 // total cost per shard
+	// estimated bytes, only tracked with a memory budget
+	Memory      int64
+	ShardMemory []int64
+	Janitor     JanitorStats
+}
+
+type JanitorStats struct {
+	Sweeps      uint64        // shard sweeps
+	Expired     uint64        // entries removed
+	Cutoffs     uint64        // sweeps stopped by the budget or max lock hold
+	Batches     uint64        // lock acquisitions, one per sweep unless incremental
+	LockHeld    time.Duration // total time shard locks were held
+	MaxLockHeld time.Duration // longest a shard lock was held at once
 }
@@ -0,0 +1,127 @@
+package core
+
+import "time"
+
+// SetWithSlidingTTL stores val under key with an idle timeout: every read
+// pushes its expiry ttl into the future again.
+func (s *Shard[K, V]) SetWithSlidingTTL(key K, val V, ttl time.Duration) (success bool, evicted int) {
+	size := s.size(key, val)
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry := s.newEntry(key, val, size, ttl)
+	entry.sliding = ttl
+	return s.store(key, entry)
+}
+
+// Touch lets key expire ttl from now without rewriting its value or
+// affecting the policy. For sliding entries ttl becomes the new idle
+// timeout. Returns false if key is missing or expired, or when the shard
+// has no default TTL: nothing expires then.
+func (s *Shard[K, V]) Touch(key K, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.unlock()
+
+	now := s.now()
+	entry, ok := s.live(key, now)
+	if !ok || s.defaultTTL == 0 {
+		return false
+	}
+	if entry.sliding > 0 {
+		entry.sliding = ttl
+	}
+	s.expire(key, entry, now.Add(ttl), now)
+	return true
+}
+
+// Expire lets key expire at a fixed time, a sliding entry stops sliding.
+// Returns false if key is missing or expired, or when the shard has no
+// default TTL.
+func (s *Shard[K, V]) Expire(key K, at time.Time) bool {
+	s.mu.Lock()
+	defer s.unlock()
+
+	now := s.now()
+	entry, ok := s.live(key, now)
+	if !ok || s.defaultTTL == 0 {
+		return false
+	}
+	entry.sliding = 0
+	s.expire(key, entry, at, now)
+	return true
+}
+
+// expire moves the expiry of entry to at, must hold the lock.
+func (s *Shard[K, V]) expire(key K, entry Entry[V], at, now time.Time) {
+	entry.expiresAt = at.UnixNano()
+	if at.Before(time.Unix(0, 1)) {
+		// UnixNano is undefined before 1970, those times expired anyway
+		entry.expiresAt = 1
+	}
+	s.Store[key] = entry
+	// a persisted entry is indexed again
+	s.expiry.Schedule(key, at.Add(s.stale))
+}
+
+// Persist removes the expiry of key, like Redis' PERSIST. Returns false if
+// key is missing, expired or has no expiry.
+func (s *Shard[K, V]) Persist(key K) bool {
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, ok := s.live(key, s.now())
+	if !ok || entry.expiresAt == 0 || s.defaultTTL == 0 {
+		return false
+	}
+	entry.expiresAt = 0
+	entry.sliding = 0
+	s.Store[key] = entry
+	s.expiry.Cancel(key)
+	return true
+}
+
+// GetWithExpiry is like GetRefresh, but also returns when the entry expires,
+// the zero time if it doesn't.
+func (s *Shard[K, V]) GetWithExpiry(key K) (val V, expiresAt time.Time, hit bool, refresh bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	val, hit, refresh = s.get(key, s.now())
+	if !hit || s.defaultTTL == 0 {
+		return val, time.Time{}, hit, refresh
+	}
+	// get may have extended a sliding expiry
+	return val, s.Store[key].expiry(), true, refresh
+}
+
+// slide extends the expiry of a sliding entry that hasn't expired yet.
+// Returns whether the entry changed, must hold the lock.
+func (s *Shard[K, V]) slide(key K, entry *Entry[V], now time.Time) bool {
+	if entry.sliding == 0 || entry.expiresAt <= now.UnixNano() {
+		return false
+	}
+	at := now.Add(entry.sliding)
+	entry.expiresAt = at.UnixNano()
+	s.expiry.Reschedule(key, at.Add(s.stale))
+	return true
+}
+
+// NoExpiry is the TTL of entries that don't expire.
+const NoExpiry time.Duration = -1
+
+// TTL returns the remaining lifetime of key, NoExpiry if it doesn't expire,
+// without affecting the policy. Returns false if key is missing or expired.
+func (s *Shard[K, V]) TTL(key K) (time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	entry, ok := s.Store[key]
+	if !ok || s.expired(entry, now) {
+		return 0, false
+	}
+	if s.defaultTTL == 0 || entry.expiresAt == 0 {
+		return NoExpiry, true
+	}
+	return time.Duration(entry.expiresAt - now.UnixNano()), true
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/policies"
+)
+
+func TestShard_SetWithSlidingTTL(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	s.SetWithSlidingTTL(1, 1, time.Second)
+	for range 5 {
+		n = n.Add(900 * time.Millisecond)
+		if _, hit := s.Get(1); !hit {
+			t.Fatalf("expected hit, sliding entry expired")
+		}
+	}
+	if e := s.Store[1]; !e.expiry().Equal(n.Add(time.Second)) {
+		t.Errorf("expected expiry 1s from now, got %v", e.expiry().Sub(n))
+	}
+
+	// Peek doesn't slide
+	n = n.Add(900 * time.Millisecond)
+	s.Peek(1)
+	n = n.Add(200 * time.Millisecond)
+	if _, hit := s.Get(1); hit {
+		t.Errorf("expected miss after idle timeout")
+	}
+	if s.expiry.Len() != 0 {
+		t.Errorf("expected expiry len=0, got %d", s.expiry.Len())
+	}
+
+	// janitor sees the extended expiry as well
+	s.SetWithSlidingTTL(2, 2, time.Second)
+	n = n.Add(900 * time.Millisecond)
+	s.Get(2)
+	n = n.Add(900 * time.Millisecond)
+	if queue(s).HasExpired() {
+		t.Errorf("expected expiry queue to be extended")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_Touch(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	if s.Touch(1, time.Second) {
+		t.Errorf("expected false on a miss")
+	}
+	s.SetWithTTL(1, 1, time.Second)
+	_, version, _, _ := s.GetWithVersion(1)
+	n = n.Add(900 * time.Millisecond)
+	if !s.Touch(1, time.Second) {
+		t.Errorf("expected true on a hit")
+	}
+	e := s.Store[1]
+	if !e.expiry().Equal(n.Add(time.Second)) {
+		t.Errorf("expected expiry 1s from now, got %v", e.expiry().Sub(n))
+	}
+	if e.version != version {
+		t.Errorf("expected version to be kept")
+	}
+	if e, _ := queue(s).Peek(); !e.ExpiresAt.Equal(n.Add(time.Second)) {
+		t.Errorf("expected expiry queue to be updated")
+	}
+
+	// sliding entries get a new idle timeout
+	s.SetWithSlidingTTL(2, 2, time.Second)
+	s.Touch(2, time.Minute)
+	if e := s.Store[2]; e.sliding != time.Minute {
+		t.Errorf("expected sliding=1m, got %v", e.sliding)
+	}
+}
+
+func TestShard_Touch_NoPolicyHit(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 2, time.Minute)
+	s.Set(1, 1)
+	s.Set(2, 2)
+	s.Touch(1, time.Minute)
+	s.Set(3, 3)
+
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 evicted, Touch shouldn't count as a hit")
+	}
+}
+
+func TestShard_Expire(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	if s.Expire(1, n.Add(time.Second)) {
+		t.Errorf("expected false on a miss")
+	}
+	s.SetWithSlidingTTL(1, 1, time.Second)
+	if !s.Expire(1, n.Add(2*time.Second)) {
+		t.Errorf("expected true on a hit")
+	}
+	n = n.Add(1500 * time.Millisecond)
+	s.Get(1) // doesn't slide anymore
+	n = n.Add(500 * time.Millisecond)
+	if _, hit := s.Get(1); hit {
+		t.Errorf("expected miss at the fixed expiry")
+	}
+
+	// expiring in the past
+	s.Set(2, 2)
+	s.Expire(2, n.Add(-time.Second))
+	if _, hit := s.Get(2); hit {
+		t.Errorf("expected miss")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+// Without a default TTL nothing expires, Touch and Expire report that they
+// changed nothing.
+func TestShard_Touch_Expire_NoTTL(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, 0)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.Set(1, 1)
+
+	if s.Touch(1, time.Second) {
+		t.Errorf("expected touch to fail without a default TTL")
+	}
+	if s.Expire(1, n.Add(-time.Second)) {
+		t.Errorf("expected expire to fail without a default TTL")
+	}
+	if _, hit := s.Get(1); !hit {
+		t.Errorf("expected key=1 to stay")
+	}
+}
+
+func TestShard_Persist(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	if s.Persist(1) {
+		t.Errorf("expected false on a miss")
+	}
+	s.SetWithSlidingTTL(1, 1, time.Second)
+	if !s.Persist(1) {
+		t.Errorf("expected true on a hit")
+	}
+	if s.Persist(1) {
+		t.Errorf("expected false without expiry")
+	}
+	if s.expiry.Len() != 0 {
+		t.Errorf("expected expiry len=0, got %d", s.expiry.Len())
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+
+	n = n.Add(time.Hour)
+	if _, hit := s.Get(1); !hit {
+		t.Errorf("expected persisted key to be kept")
+	}
+	if ttl, ok := s.TTL(1); ttl != NoExpiry || !ok {
+		t.Errorf("expected (NoExpiry, true), got (%v, %v)", ttl, ok)
+	}
+
+	// Touch gives it an expiry again
+	s.Touch(1, time.Second)
+	if s.expiry.Len() != 1 {
+		t.Errorf("expected expiry len=1, got %d", s.expiry.Len())
+	}
+	n = n.Add(time.Second)
+	if _, hit := s.Get(1); hit {
+		t.Errorf("expected miss")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_TTL(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	if _, ok := s.TTL(1); ok {
+		t.Errorf("expected false on a miss")
+	}
+	s.SetWithTTL(1, 1, time.Second)
+	n = n.Add(400 * time.Millisecond)
+	if ttl, ok := s.TTL(1); ttl != 600*time.Millisecond || !ok {
+		t.Errorf("expected (600ms, true), got (%v, %v)", ttl, ok)
+	}
+	n = n.Add(600 * time.Millisecond)
+	if _, ok := s.TTL(1); ok {
+		t.Errorf("expected false when expired")
+	}
+
+	// without a default TTL nothing expires
+	s = InitShard[int, int](policies.NewLRU[int](), 10, 0)
+	s.Set(1, 1)
+	if ttl, ok := s.TTL(1); ttl != NoExpiry || !ok {
+		t.Errorf("expected (NoExpiry, true), got (%v, %v)", ttl, ok)
+	}
+}
+
+func TestShard_GetWithExpiry(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	if _, _, hit, _ := s.GetWithExpiry(1); hit {
+		t.Errorf("expected miss")
+	}
+	s.SetWithTTL(1, 1, time.Second)
+	if val, at, hit, _ := s.GetWithExpiry(1); val != 1 || !at.Equal(n.Add(time.Second)) || !hit {
+		t.Errorf("expected (1, now+1s, true), got (%d, %v, %v)", val, at.Sub(n), hit)
+	}
+
+	// returns the extended expiry of sliding entries
+	s.SetWithSlidingTTL(2, 2, time.Second)
+	n = n.Add(500 * time.Millisecond)
+	if _, at, _, _ := s.GetWithExpiry(2); !at.Equal(n.Add(time.Second)) {
+		t.Errorf("expected now+1s, got %v", at.Sub(n))
+	}
+}
+
+func TestShard_Expire_Zero(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	s.Set(1, 1)
+	s.Expire(1, time.Time{})
+	if _, hit := s.Get(1); hit {
+		t.Errorf("expected miss for a key that expired at the zero time")
+	}
+}
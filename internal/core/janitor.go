@@ -1,95 +1,331 @@
 package core
 
 import (
+	"container/heap"
 	"context"
+	"runtime"
+	"sync/atomic"
 	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
+)
+
+const (
+	// DefaultJanitorInterval is used when JanitorConfig.Interval is <= 0.
+	DefaultJanitorInterval = time.Second
+	// DefaultSweepBatch is used when JanitorConfig.BatchSize is <= 0, the
+	// number of keys Redis checks per loop of its active expire cycle.
+	DefaultSweepBatch = 20
+	// DefaultExpiredRatio is used when JanitorConfig.ExpiredRatio is <= 0.
+	DefaultExpiredRatio = 0.25
+)
+
+// janitorBatch is the number of entries removed between checks of the
+// budget and the lock hold time.
+const janitorBatch = 64
+
+// SweepMode selects how the janitor removes expired entries from a shard.
+type SweepMode uint8
+
+const (
+	// SweepLocked removes the expired entries of a shard under a single lock
+	// acquisition, bounded by Budget and MaxLockHold.
+	SweepLocked SweepMode = iota
+	// SweepIncremental removes at most BatchSize entries per lock
+	// acquisition and yields between batches, so readers aren't stalled by a
+	// burst of expiries. Batches repeat while more than ExpiredRatio of the
+	// entries examined by the last one had expired, like the active expire
+	// cycle of Redis. Shards without an expiry index are sampled at random.
+	SweepIncremental
 )
 
+// JanitorConfig configures a Janitor.
+type JanitorConfig struct {
+	// Interval is the longest a shard goes without a sweep, and the delay of
+	// the first one. Shards are swept earlier when an entry expires earlier.
+	Interval time.Duration
+	// Budget is the maximum number of keys a sweep examines: takes from the
+	// index of a shard, or samples in incremental mode. 0 means no limit.
+	Budget int
+	// MaxLockHold is the maximum time a sweep holds the lock of a shard, 0
+	// means no limit. Incremental sweeps are bounded by BatchSize instead.
+	MaxLockHold time.Duration
+	Mode        SweepMode
+	// BatchSize is the number of keys examined per lock acquisition in
+	// incremental mode.
+	BatchSize int
+	// ExpiredRatio is the share of expired keys in a batch above which an
+	// incremental sweep continues.
+	ExpiredRatio float64
+}
+
+// Janitor removes expired entries from a set of shards in the background,
+// with a single goroutine that sweeps each shard when it is due. A sweep
+// that runs out of budget or lock time continues after the other due shards.
 type Janitor[K comparable, V any] struct {
-	shard  *Shard[K, V]
+	shards []*Shard[K, V]
+	cfg    JanitorConfig
+	queue  deadlines
 	cancel context.CancelFunc
 	result chan uint64
+
+	sweeps      atomic.Uint64
+	expired     atomic.Uint64
+	cutoffs     atomic.Uint64
+	batches     atomic.Uint64
+	lockHeld    atomic.Int64
+	maxLockHeld atomic.Int64
 }
 
-func StartJanitor[K comparable, V any](s *Shard[K, V], initialDelay time.Duration) *Janitor[K, V] {
-	janitor := &Janitor[K, V]{
-		shard:  s,
-		result: make(chan uint64),
-	}
+func StartJanitor[K comparable, V any](shards []*Shard[K, V], cfg JanitorConfig) *Janitor[K, V] {
+	janitor := newJanitor(shards, cfg)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	janitor.cancel = cancel
 
-	go janitor.run(ctx, initialDelay)
+	go janitor.run(ctx)
 
 	return janitor
 }
 
-func (j *Janitor[K, V]) Start(initialDelay time.Duration) {
-	ctx, cancel := context.WithCancel(context.Background())
-	j.cancel = cancel
+// newJanitor returns a janitor that isn't running yet.
+func newJanitor[K comparable, V any](shards []*Shard[K, V], cfg JanitorConfig) *Janitor[K, V] {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultJanitorInterval
+	}
+	cfg.Budget = max(cfg.Budget, 0)
+	cfg.MaxLockHold = max(cfg.MaxLockHold, 0)
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultSweepBatch
+	}
+	if cfg.ExpiredRatio <= 0 {
+		cfg.ExpiredRatio = DefaultExpiredRatio
+	}
 
-	go j.run(ctx, initialDelay)
+	janitor := &Janitor[K, V]{
+		shards: shards,
+		cfg:    cfg,
+		result: make(chan uint64),
+	}
+	for i, s := range shards {
+		janitor.queue.items = append(janitor.queue.items, deadline{at: s.now().Add(cfg.Interval), shard: i})
+	}
+	heap.Init(&janitor.queue)
+	return janitor
 }
 
-func (j *Janitor[K, V]) run(ctx context.Context, initialDelay time.Duration) {
-	timer := time.NewTimer(initialDelay)
+func (j *Janitor[K, V]) run(ctx context.Context) {
+	timer := time.NewTimer(j.cfg.Interval)
 	defer timer.Stop()
 
-	var expired uint64
-
 	for {
 		select {
 		case <-ctx.Done():
 			// final sweep before exiting for consistency
-			j.shard.mu.Lock()
-			for j.shard.expiry.HasExpired() {
-				victim := j.shard.expiry.PopMin().K
-				if _, ok := j.shard.Store[victim]; ok {
-					delete(j.shard.Store, victim)
-					j.shard.Policy.OnDel(victim)
-					expired++
-				}
+			var expired uint64
+			for _, s := range j.shards {
+				s.mu.Lock()
+				n, _ := s.sweep(s.now(), 0)
+				s.unlock()
+				expired += uint64(n)
 			}
-			j.shard.mu.Unlock()
+			j.expired.Add(expired)
 
-			j.result <- expired
+			j.result <- j.expired.Load()
 			close(j.result)
 			return
 		case <-timer.C:
-			j.shard.mu.Lock()
-			// cleanup
-			for j.shard.expiry.HasExpired() {
-				victim := j.shard.expiry.PopMin().K
-				if _, ok := j.shard.Store[victim]; ok {
-					delete(j.shard.Store, victim)
-					j.shard.Policy.OnDel(victim)
-					expired++
+			d := j.cfg.Interval
+			for len(j.queue.items) > 0 {
+				next := j.queue.items[0]
+				s := j.shards[next.shard]
+				if d = next.at.Sub(s.now()); d > 0 {
+					break
 				}
+				j.queue.update(j.sweep(s))
 			}
+			timer.Reset(d)
+		}
+	}
+}
 
-			// determine next sweep
-			var d time.Duration
-			if e, ok := j.shard.expiry.Peek(); ok {
-				d = max(time.Until(e.ExpiresAt), 0)
-			} else {
-				d = initialDelay
-			}
-			j.shard.mu.Unlock()
+// sweep removes expired entries from s, and returns when s is due again.
+func (j *Janitor[K, V]) sweep(s *Shard[K, V]) time.Time {
+	if j.cfg.Mode == SweepIncremental {
+		return j.sweepIncremental(s)
+	}
+	return j.sweepLocked(s)
+}
 
-			// drain timer
-			if !timer.Stop() {
-				select {
-				case <-timer.C:
-				default:
-				}
-			}
-			timer.Reset(d)
+// sweepLocked removes expired entries from s under a single lock, within the
+// budget and lock hold time.
+func (j *Janitor[K, V]) sweepLocked(s *Shard[K, V]) time.Time {
+	s.mu.Lock()
+	start := time.Now()
+	now := s.now()
+
+	var expired, popped int
+	cutoff := false
+	for {
+		limit := janitorBatch
+		if j.cfg.Budget > 0 {
+			limit = min(limit, j.cfg.Budget-popped)
+		}
+		n, p := s.sweep(now, limit)
+		expired += n
+		popped += p
+		if p < limit {
+			break // nothing left to remove
+		}
+		if (j.cfg.Budget > 0 && popped >= j.cfg.Budget) ||
+			(j.cfg.MaxLockHold > 0 && time.Since(start) >= j.cfg.MaxLockHold) {
+			cutoff = true
+			break
+		}
+	}
+
+	next := j.next(s, now, cutoff)
+	held := time.Since(start)
+	s.unlock()
+
+	j.record(held)
+	j.finish(expired, cutoff)
+	return next
+}
+
+// sweepIncremental removes expired entries from s in batches, each under its
+// own lock acquisition, and yields between batches. Shards without an expiry
+// index are sampled instead.
+func (j *Janitor[K, V]) sweepIncremental(s *Shard[K, V]) time.Time {
+	_, sample := s.expiry.(ttl_queue.NoIndex[K])
+
+	var expired, examined int
+	for {
+		limit := j.cfg.BatchSize
+		if j.cfg.Budget > 0 {
+			limit = min(limit, j.cfg.Budget-examined)
+		}
+
+		s.mu.Lock()
+		start := time.Now()
+		now := s.now()
+		var n, m int
+		if sample {
+			n, m = s.sample(now, limit)
+		} else {
+			n, m = s.sweep(now, limit)
+		}
+		expired += n
+		examined += m
+		// a short batch means nothing is left to examine
+		done := m < limit || float64(n) <= j.cfg.ExpiredRatio*float64(m)
+		cutoff := !done && j.cfg.Budget > 0 && examined >= j.cfg.Budget
+		var next time.Time
+		if done || cutoff {
+			next = j.next(s, now, cutoff)
+		}
+		held := time.Since(start)
+		s.unlock()
+
+		j.record(held)
+		if done || cutoff {
+			j.finish(expired, cutoff)
+			return next
+		}
+		runtime.Gosched()
+	}
+}
+
+// next returns when s is due again: right away if its sweep was cut off,
+// otherwise at its next deadline or after the interval. Must hold the lock.
+func (j *Janitor[K, V]) next(s *Shard[K, V], now time.Time, cutoff bool) time.Time {
+	if cutoff {
+		return now
+	}
+	next := now.Add(j.cfg.Interval)
+	if at, ok := s.expiry.NextDeadline(); ok && at.Before(next) {
+		next = at
+	}
+	return next
+}
+
+// record adds a lock acquisition that lasted held to the stats.
+func (j *Janitor[K, V]) record(held time.Duration) {
+	j.batches.Add(1)
+	j.lockHeld.Add(int64(held))
+	for {
+		m := j.maxLockHeld.Load()
+		if int64(held) <= m || j.maxLockHeld.CompareAndSwap(m, int64(held)) {
+			break
 		}
 	}
 }
 
+// finish adds a completed sweep to the stats.
+func (j *Janitor[K, V]) finish(expired int, cutoff bool) {
+	j.sweeps.Add(1)
+	j.expired.Add(uint64(expired))
+	if cutoff {
+		j.cutoffs.Add(1)
+	}
+}
+
+// Stats returns the work the janitor has done so far.
+func (j *Janitor[K, V]) Stats() JanitorStats {
+	return JanitorStats{
+		Sweeps:      j.sweeps.Load(),
+		Expired:     j.expired.Load(),
+		Cutoffs:     j.cutoffs.Load(),
+		Batches:     j.batches.Load(),
+		LockHeld:    time.Duration(j.lockHeld.Load()),
+		MaxLockHeld: time.Duration(j.maxLockHeld.Load()),
+	}
+}
+
+// Stop stops the janitor after a final sweep without limits, and returns the
+// number of entries it removed in total.
 func (j *Janitor[K, V]) Stop() (expired uint64) {
 	j.cancel()
 	return <-j.result
 }
+
+type deadline struct {
+	at    time.Time
+	shard int
+	seq   uint64
+}
+
+// deadlines is a min-heap of the shards' next sweeps, ties are broken by
+// insertion order so due shards take turns.
+type deadlines struct {
+	items []deadline
+	seq   uint64
+}
+
+func (d *deadlines) Len() int { return len(d.items) }
+
+func (d *deadlines) Less(i, j int) bool {
+	x, y := d.items[i], d.items[j]
+	if x.at.Equal(y.at) {
+		return x.seq < y.seq
+	}
+	return x.at.Before(y.at)
+}
+
+func (d *deadlines) Swap(i, j int) { d.items[i], d.items[j] = d.items[j], d.items[i] }
+
+func (d *deadlines) Push(x any) { d.items = append(d.items, x.(deadline)) }
+
+func (d *deadlines) Pop() any {
+	x := d.items[len(d.items)-1]
+	d.items = d.items[:len(d.items)-1]
+	return x
+}
+
+// update moves the first shard to at.
+func (d *deadlines) update(at time.Time) {
+	d.seq++
+	d.items[0].at = at
+	d.items[0].seq = d.seq
+	heap.Fix(d, 0)
+}
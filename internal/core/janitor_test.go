@@ -1,10 +1,12 @@
 package core
 
 import (
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 func TestJanitor_Start(t *testing.T) {
@@ -13,14 +15,21 @@ func TestJanitor_Start(t *testing.T) {
 		100,
 		5*time.Minute,
 	)
-	j := StartJanitor(s, 0)
-	if !j.shard.Equals(s) {
+	j := StartJanitor([]*Shard[int, int]{s}, JanitorConfig{})
+	defer j.Stop()
+	if !j.shards[0].Equals(s) {
 		t.Errorf("expected true, got false")
 	}
 	s.Set(1, 1)
-	if !j.shard.Equals(s) {
+	if !j.shards[0].Equals(s) {
 		t.Errorf("expected true, got false")
 	}
+	if j.cfg.Interval != DefaultJanitorInterval {
+		t.Errorf("expected interval=%v, got %v", DefaultJanitorInterval, j.cfg.Interval)
+	}
+	if j.cfg.Mode != SweepLocked || j.cfg.BatchSize != DefaultSweepBatch || j.cfg.ExpiredRatio != DefaultExpiredRatio {
+		t.Errorf("expected locked sweeps with default batch and ratio, got %+v", j.cfg)
+	}
 }
 
 func TestJanitor_Stop(t *testing.T) {
@@ -29,10 +38,10 @@ func TestJanitor_Stop(t *testing.T) {
 		100,
 		5*time.Minute,
 	)
-	j := StartJanitor(s, 0)
 	n := time.Now()
 	now := func() time.Time { return n }
 	s.setNow(now)
+	j := StartJanitor([]*Shard[int, int]{s}, JanitorConfig{Interval: time.Hour})
 
 	s.SetWithTTL(1, 1, -50)
 	s.SetWithTTL(2, 2, -50)
@@ -43,9 +52,212 @@ func TestJanitor_Stop(t *testing.T) {
 	if expired != 2 {
 		t.Errorf("expected 2, got %d", expired)
 	}
-	if e, ok := s.expiry.Peek(); !ok {
+	if e, ok := queue(s).Peek(); !ok {
 		t.Errorf("expected true, got false")
 	} else if e.K != 3 {
 		t.Errorf("expected k=3, got %d", e.K)
 	}
 }
+
+func TestJanitor_SingleGoroutine(t *testing.T) {
+	shards := make([]*Shard[int, int], 64)
+	for i := range shards {
+		shards[i] = InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	}
+	before := runtime.NumGoroutine()
+	j := StartJanitor(shards, JanitorConfig{})
+	if d := runtime.NumGoroutine() - before; d != 1 {
+		t.Errorf("expected 1 goroutine for 64 shards, got %d", d)
+	}
+	j.Stop()
+}
+
+func TestJanitor_Run(t *testing.T) {
+	shards := make([]*Shard[int, int], 4)
+	for i := range shards {
+		shards[i] = InitShard[int, int](policies.NewFIFO[int](), 100, time.Minute)
+		shards[i].SetWithTTL(1, 1, time.Millisecond)
+		shards[i].SetWithTTL(2, 2, time.Hour)
+	}
+	j := StartJanitor(shards, JanitorConfig{Interval: 5 * time.Millisecond})
+	defer j.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for j.Stats().Expired < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 expired entries, got %d", j.Stats().Expired)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, s := range shards {
+		s.mu.RLock()
+		if l := len(s.Store); l != 1 {
+			t.Errorf("expected len=1 for shard %d, got %d", i, l)
+		}
+		s.mu.RUnlock()
+	}
+	if stats := j.Stats(); stats.Sweeps < 4 || stats.LockHeld <= 0 || stats.MaxLockHeld <= 0 {
+		t.Errorf("expected sweeps and lock time to be recorded, got %+v", stats)
+	}
+}
+
+func TestJanitor_Budget(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	for i := range 100 {
+		s.SetWithTTL(i, i, -50)
+	}
+	// not started, sweeps are driven by the test
+	j := &Janitor[int, int]{
+		shards: []*Shard[int, int]{s},
+		cfg:    JanitorConfig{Interval: time.Hour, Budget: 30},
+	}
+
+	if next := j.sweep(s); !next.Equal(n) {
+		t.Errorf("expected shard due again right away, got %v", next.Sub(n))
+	}
+	if l := len(s.Store); l != 70 {
+		t.Errorf("expected len=70, got %d", l)
+	}
+	for range 3 {
+		j.sweep(s)
+	}
+	if l := len(s.Store); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+	if next := j.sweep(s); !next.Equal(n.Add(time.Hour)) {
+		t.Errorf("expected next sweep after the interval, got %v", next.Sub(n))
+	}
+	if stats := j.Stats(); stats.Sweeps != 5 || stats.Expired != 100 || stats.Cutoffs != 3 {
+		t.Errorf("expected 5 sweeps, 100 expired and 3 cutoffs, got %+v", stats)
+	}
+}
+
+func TestJanitor_MaxLockHold(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	for i := range 100 {
+		s.SetWithTTL(i, i, -50)
+	}
+	j := &Janitor[int, int]{
+		shards: []*Shard[int, int]{s},
+		cfg:    JanitorConfig{Interval: time.Hour, MaxLockHold: time.Nanosecond},
+	}
+
+	// stops after the first batch
+	j.sweep(s)
+	if l := len(s.Store); l != 100-janitorBatch {
+		t.Errorf("expected len=%d, got %d", 100-janitorBatch, l)
+	}
+	if stats := j.Stats(); stats.Cutoffs != 1 {
+		t.Errorf("expected 1 cutoff, got %d", stats.Cutoffs)
+	}
+}
+
+// Shards are swept when their next entry expires, before the interval.
+func TestJanitor_NextDeadline(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetWithTTL(1, 1, time.Second)
+	j := &Janitor[int, int]{
+		shards: []*Shard[int, int]{s},
+		cfg:    JanitorConfig{Interval: time.Hour},
+	}
+
+	if next := j.sweep(s); !next.Equal(n.Add(time.Second)) {
+		t.Errorf("expected next sweep in 1s, got %v", next.Sub(n))
+	}
+}
+
+func TestJanitor_Incremental(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	for i := range 100 {
+		s.SetWithTTL(i, i, -50)
+	}
+	s.SetWithTTL(100, 100, time.Second)
+	var removed []int
+	s.SetRemovalListener(func(k, v int, reason RemovalReason) {
+		// listeners run between batches, the lock isn't held
+		if !s.mu.TryLock() {
+			t.Errorf("expected shard to be unlocked")
+			return
+		}
+		s.mu.Unlock()
+		removed = append(removed, k)
+	})
+	j := newJanitor([]*Shard[int, int]{s}, JanitorConfig{Interval: time.Hour, Mode: SweepIncremental, BatchSize: 10})
+
+	if next := j.sweep(s); !next.Equal(n.Add(time.Second)) {
+		t.Errorf("expected next sweep in 1s, got %v", next.Sub(n))
+	}
+	if l := len(s.Store); l != 1 {
+		t.Errorf("expected len=1, got %d", l)
+	}
+	if len(removed) != 100 {
+		t.Errorf("expected 100 removals, got %d", len(removed))
+	}
+	// 10 full batches and a short one
+	if stats := j.Stats(); stats.Sweeps != 1 || stats.Batches != 11 || stats.Expired != 100 || stats.Cutoffs != 0 {
+		t.Errorf("expected 1 sweep in 11 batches removing 100, got %+v", stats)
+	}
+}
+
+func TestJanitor_Incremental_Budget(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	for i := range 100 {
+		s.SetWithTTL(i, i, -50)
+	}
+	j := newJanitor([]*Shard[int, int]{s}, JanitorConfig{Interval: time.Hour, Budget: 25, Mode: SweepIncremental, BatchSize: 10})
+
+	if next := j.sweep(s); !next.Equal(n) {
+		t.Errorf("expected shard due again right away, got %v", next.Sub(n))
+	}
+	if l := len(s.Store); l != 75 {
+		t.Errorf("expected len=75, got %d", l)
+	}
+	if stats := j.Stats(); stats.Batches != 3 || stats.Cutoffs != 1 {
+		t.Errorf("expected 3 batches and a cutoff, got %+v", stats)
+	}
+}
+
+// Without an index entries are sampled, sweeps continue while most of a
+// sample had expired.
+func TestJanitor_Incremental_Sample(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1000, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetExpiryIndex(ttl_queue.NoIndex[int]{})
+	for i := range 100 {
+		s.SetWithTTL(i, i, -50)
+	}
+	j := newJanitor([]*Shard[int, int]{s}, JanitorConfig{Interval: time.Hour, Mode: SweepIncremental})
+
+	if next := j.sweep(s); !next.Equal(n.Add(time.Hour)) {
+		t.Errorf("expected next sweep after the interval, got %v", next.Sub(n))
+	}
+	if l := len(s.Store); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+	if stats := j.Stats(); stats.Batches != 100/DefaultSweepBatch+1 || stats.Expired != 100 {
+		t.Errorf("expected %d batches removing 100, got %+v", 100/DefaultSweepBatch+1, stats)
+	}
+
+	// stops after a sample without expired entries
+	for i := range 1000 {
+		s.SetWithTTL(i, i, time.Minute)
+	}
+	j.sweep(s)
+	if stats := j.Stats(); stats.Batches != 100/DefaultSweepBatch+2 {
+		t.Errorf("expected a single batch, got %d", stats.Batches-100/DefaultSweepBatch-1)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
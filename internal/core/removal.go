@@ -0,0 +1,64 @@
+package core
+
+// RemovalReason tells a removal listener why an entry left the cache.
+type RemovalReason uint8
+
+const (
+	ReasonEvicted  RemovalReason = iota + 1 // evicted to make room
+	ReasonExpired                           // TTL ran out
+	ReasonDeleted                           // removed by Del
+	ReasonReplaced                          // value overwritten by a set
+	ReasonFlushed                           // removed by Flush
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonFlushed:
+		return "flushed"
+	default:
+		return "unknown"
+	}
+}
+
+type removal[K comparable, V any] struct {
+	key    K
+	val    V
+	reason RemovalReason
+}
+
+// SetRemovalListener sets the function called for every entry that leaves
+// the shard, nil disables it. Removals are queued while the shard is locked
+// and delivered after it is unlocked, in order, on the goroutine that
+// caused them, so the listener may call back into the shard.
+func (s *Shard[K, V]) SetRemovalListener(f func(K, V, RemovalReason)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = f
+}
+
+// queueRemoval records a removal for the listener, must hold the lock.
+func (s *Shard[K, V]) queueRemoval(key K, val V, reason RemovalReason) {
+	if s.listener != nil {
+		s.removed = append(s.removed, removal[K, V]{key, val, reason})
+	}
+}
+
+// unlock releases the write lock and then delivers the removals queued
+// while it was held.
+func (s *Shard[K, V]) unlock() {
+	removed, listener := s.removed, s.listener
+	s.removed = nil
+	s.mu.Unlock()
+
+	for _, r := range removed {
+		listener(r.key, r.val, r.reason)
+	}
+}
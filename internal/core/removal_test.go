@@ -0,0 +1,113 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/policies"
+)
+
+type removalLog struct {
+	keys    []int
+	reasons []RemovalReason
+}
+
+func (l *removalLog) record(k, v int, reason RemovalReason) {
+	l.keys = append(l.keys, k)
+	l.reasons = append(l.reasons, reason)
+}
+
+func TestRemovalReason_String(t *testing.T) {
+	if s := ReasonReplaced.String(); s != "replaced" {
+		t.Errorf("expected 'replaced', got %s", s)
+	}
+	if s := RemovalReason(0).String(); s != "unknown" {
+		t.Errorf("expected 'unknown', got %s", s)
+	}
+}
+
+func TestShard_RemovalListener(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 2, 100)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	var log removalLog
+	s.SetRemovalListener(log.record)
+
+	s.Set(1, 1)
+	s.Set(1, 10) // replaced
+	s.Set(2, 2)
+	s.Set(3, 3) // evicts 1
+	s.Del(2)    // deleted
+	s.SetWithTTL(4, 4, -50)
+	s.Get(4)   // expired
+	s.Flush()  // flushes 3
+	s.Del(100) // not found, no removal
+
+	wantKeys := []int{1, 1, 2, 4, 3}
+	wantReasons := []RemovalReason{
+		ReasonReplaced, ReasonEvicted, ReasonDeleted, ReasonExpired, ReasonFlushed,
+	}
+	if !slices.Equal(log.keys, wantKeys) {
+		t.Errorf("expected keys %v, got %v", wantKeys, log.keys)
+	}
+	if !slices.Equal(log.reasons, wantReasons) {
+		t.Errorf("expected reasons %v, got %v", wantReasons, log.reasons)
+	}
+}
+
+func TestShard_RemovalListener_Sweep(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, 100)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	var log removalLog
+	s.SetRemovalListener(log.record)
+
+	s.SetWithTTL(1, 1, -50)
+	s.SetWithTTL(2, 2, 50)
+	s.Get(2) // budget sweep removes 1
+
+	if !slices.Equal(log.keys, []int{1}) || log.reasons[0] != ReasonExpired {
+		t.Errorf("expected key=1 expired, got %v %v", log.keys, log.reasons)
+	}
+}
+
+func TestShard_RemovalListener_Janitor(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, 5*time.Minute)
+	var log removalLog
+	s.SetRemovalListener(log.record)
+	j := StartJanitor([]*Shard[int, int]{s}, JanitorConfig{Interval: time.Hour})
+
+	s.SetWithTTL(1, 1, -50)
+	j.Stop() // final sweep
+
+	if !slices.Equal(log.keys, []int{1}) || log.reasons[0] != ReasonExpired {
+		t.Errorf("expected key=1 expired, got %v %v", log.keys, log.reasons)
+	}
+}
+
+// the listener runs outside the lock, so it can use the shard
+func TestShard_RemovalListener_Reentrant(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 1, 0)
+	s.SetRemovalListener(func(k, v int, reason RemovalReason) {
+		if reason == ReasonEvicted && k < 100 {
+			s.Get(k)
+			s.Set(k+100, v)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.Set(1, 1)
+		s.Set(2, 2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listener deadlocked the shard")
+	}
+	if len(s.removed) != 0 {
+		t.Errorf("expected no queued removals, got %d", len(s.removed))
+	}
+}
@@ -7,12 +7,26 @@ import (
 	"time"
 
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
 	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 type Entry[V any] struct {
 	val       V
-	expiresAt time.Time
+	expiresAt int64 // unix nanos, 0 when persisted, see Persist
+	refreshAt int64 // unix nanos, 0 without refresh-ahead
+	cost      int64
+	size      int64         // estimated bytes, only measured with a memory budget
+	version   uint64        // changes on every write, see GetWithVersion
+	sliding   time.Duration // idle timeout, 0 for a fixed expiry
+}
+
+// expiry returns when the entry expires, the zero time if it doesn't.
+func (e Entry[V]) expiry() time.Time {
+	if e.expiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, e.expiresAt)
 }
 
 type Shard[K comparable, V any] struct {
@@ -20,9 +34,27 @@ type Shard[K comparable, V any] struct {
 	Store      map[K]Entry[V]
 	Policy     policies.Policy[K]
 	cap        int
-	expiry     *ttl_queue.TTLQueue[K]
+	maxCost    int64 // 0 means no limit
+	cost       int64
+	weigher    func(K, V) int64
+	maxMemory  int64 // bytes, 0 means no limit
+	memory     int64
+	sizer      func(V) int64
+	listener   func(K, V, RemovalReason)
+	removed    []removal[K, V] // queued for listener until unlock
+	expiry     ttl_queue.ExpiryIndex[K]
+	popped     []K // scratch space for expired keys
 	defaultTTL time.Duration
-	now        func() time.Time
+	// refresh-ahead and stale-while-revalidate, see SetRefresh
+	refreshAfter time.Duration
+	stale        time.Duration
+	now          func() time.Time
+	clock        uint64 // logical clock for sampling policies
+	// access metadata for sampling policies, nil for other policies so
+	// entries don't pay for it. Keys get it in touch and lose it in
+	// unstore and Flush, the only places keys leave the store.
+	meta    map[K]policies.Sample
+	version uint64 // last version handed out, never reset
 }
 
 func InitShard[K comparable, V any](Policy policies.Policy[K], cap int, defaultTTL time.Duration) *Shard[K, V] {
@@ -37,52 +69,141 @@ func InitShard[K comparable, V any](Policy policies.Policy[K], cap int, defaultT
 }
 
 func (s *Shard[K, V]) SetWithTTL(key K, val V, ttl time.Duration) (success bool, evicted int) {
+	size := s.size(key, val)
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
-	entry := Entry[V]{
-		val:       val,
-		expiresAt: s.now().Add(ttl),
-	}
-	s.expiry.PushWithTTL(key, ttl)
-	return s.set(key, entry)
+	return s.put(key, val, size, ttl)
 }
 
 func (s *Shard[K, V]) Set(key K, val V) (success bool, evicted int) {
+	size := s.size(key, val)
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
+
+	return s.put(key, val, size, s.defaultTTL)
+}
+
+// SetMany sets keys[i] to vals[i] under a single lock and records in
+// success[i] whether it was stored. Returns the number of evicted entries.
+func (s *Shard[K, V]) SetMany(keys []K, vals []V, success []bool) (evicted int) {
+	sizes := s.sizes(keys, vals)
+	s.mu.Lock()
+	defer s.unlock()
+	return s.setMany(keys, vals, sizes, s.defaultTTL, success)
+}
+
+// SetManyWithTTL is like SetMany, with ttl for all keys.
+func (s *Shard[K, V]) SetManyWithTTL(keys []K, vals []V, ttl time.Duration, success []bool) (evicted int) {
+	sizes := s.sizes(keys, vals)
+	s.mu.Lock()
+	defer s.unlock()
+	return s.setMany(keys, vals, sizes, ttl, success)
+}
 
-	entry := Entry[V]{
+// setMany puts every key, sizes are from s.sizes. Must hold the lock.
+func (s *Shard[K, V]) setMany(keys []K, vals []V, sizes []int64, ttl time.Duration, success []bool) (evicted int) {
+	for i, key := range keys {
+		var size int64
+		if sizes != nil {
+			size = sizes[i]
+		}
+		ok, n := s.put(key, vals[i], size, ttl)
+		success[i] = ok
+		evicted += n
+	}
+	return evicted
+}
+
+// SetWithCost is like Set, but uses cost instead of asking the weigher.
+// Negative costs count as 0.
+func (s *Shard[K, V]) SetWithCost(key K, val V, cost int64) (success bool, evicted int) {
+	size := s.size(key, val)
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry := s.newEntry(key, val, size, s.defaultTTL)
+	entry.cost = max(cost, 0)
+	return s.store(key, entry)
+}
+
+// put stores val under key with ttl, size is the estimate from s.size.
+// Must hold the lock.
+func (s *Shard[K, V]) put(key K, val V, size int64, ttl time.Duration) (success bool, evicted int) {
+	return s.store(key, s.newEntry(key, val, size, ttl))
+}
+
+// store sets a new entry and indexes its expiry, unless the write was
+// rejected and the old entry (and its deadline) stays. Must hold the lock.
+func (s *Shard[K, V]) store(key K, entry Entry[V]) (success bool, evicted int) {
+	if success, evicted = s.set(key, entry); success {
+		s.schedule(key, entry)
+	}
+	return success, evicted
+}
+
+func (s *Shard[K, V]) newEntry(key K, val V, size int64, ttl time.Duration) Entry[V] {
+	return Entry[V]{
 		val:       val,
-		expiresAt: s.now().Add(s.defaultTTL),
+		expiresAt: s.now().Add(ttl).UnixNano(),
+		refreshAt: s.refreshAt(),
+		cost:      s.weigh(key, val),
+		size:      s.withOverhead(size),
 	}
-	s.expiry.PushStd(key)
-	return s.set(key, entry)
 }
 
 func (s *Shard[K, V]) set(key K, entry Entry[V]) (success bool, evicted int) {
-	_, exists := s.Store[key]
-
-	if !exists && s.cap > 0 {
-		attempts := 0
-		for len(s.Store) >= s.cap {
-			victim, ok := s.Policy.Evict()
-			if !ok {
-				return false, evicted
+	old, exists := s.Store[key]
+
+	if s.maxCost > 0 && entry.cost > s.maxCost {
+		return false, evicted
+	}
+	if s.maxMemory > 0 && entry.size > s.maxMemory {
+		return false, evicted
+	}
+	if !exists && s.overflows(key, entry) && !s.admit(key) {
+		return false, evicted
+	}
+	attempts := 0
+	// key itself may be picked to make room for its new value, it's only
+	// reported as evicted when the set fails
+	var self Entry[V]
+	selfEvicted := false
+	for s.overflows(key, entry) {
+		victim, ok := s.evict()
+		if !ok {
+			return false, s.failSet(key, self, selfEvicted, evicted)
+		}
+		if victim == key {
+			if e, ok := s.unstore(key); ok {
+				self, selfEvicted = e, true
+				s.expiry.Cancel(key)
+				continue
 			}
-			if _, present := s.Store[victim]; present {
-				delete(s.Store, victim)
-				evicted++ // only increases when evicted from Store (not Policy)
-			} else {
-				attempts++
-				if attempts > s.cap {
-					return false, evicted
-				}
+		}
+		if _, present := s.drop(victim, ReasonEvicted); present {
+			s.expiry.Cancel(victim)
+			evicted++ // only increases when evicted from Store (not Policy)
+		} else {
+			attempts++
+			if attempts > max(s.cap, len(s.Store)) {
+				return false, s.failSet(key, self, selfEvicted, evicted)
 			}
 		}
 	}
+	old, exists = s.Store[key]
+	if exists {
+		s.queueRemoval(key, old.val, ReasonReplaced)
+	} else if selfEvicted {
+		s.queueRemoval(key, self.val, ReasonReplaced)
+	}
 
+	s.version++
+	entry.version = s.version
+	s.touch(key)
 	s.Store[key] = entry
+	s.cost += entry.cost - old.cost
+	s.memory += entry.size - old.size
 
 	if exists {
 		s.Policy.OnHit(key)
@@ -93,41 +214,405 @@ func (s *Shard[K, V]) set(key K, entry Entry[V]) (success bool, evicted int) {
 }
 
 func (s *Shard[K, V]) Get(key K) (V, bool) {
+	val, hit, _ := s.GetRefresh(key)
+	return val, hit
+}
+
+// GetRefresh is like Get, but also reports whether the entry is due for a
+// refresh: its refresh deadline passed, or it expired and is served from
+// the stale window.
+func (s *Shard[K, V]) GetRefresh(key K) (val V, hit bool, refresh bool) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
+	return s.get(key, s.now())
+}
+
+// GetMany looks up keys under a single lock and adds the hits to out.
+// Returns the number of hits and the keys that are due for a refresh.
+func (s *Shard[K, V]) GetMany(keys []K, out map[K]V) (hits int, refresh []K) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	now := s.now()
+	for _, key := range keys {
+		val, hit, r := s.get(key, now)
+		if !hit {
+			continue
+		}
+		out[key] = val
+		hits++
+		if r {
+			refresh = append(refresh, key)
+		}
+	}
+	return hits, refresh
+}
 
+func (s *Shard[K, V]) get(key K, now time.Time) (val V, hit bool, refresh bool) {
 	entry, ok := s.Store[key]
 	if !ok {
-		var zero V
-		return zero, false
+		s.onMiss(key)
+		return val, false, false
 	}
 
-	now := s.now()
-	if s.defaultTTL != 0 && (entry.expiresAt.Before(now) || entry.expiresAt.Equal(now)) {
-		delete(s.Store, key)
-		s.Policy.OnDel(key)
-		s.expiry.Remove(key)
-		var zero V
-		return zero, false
+	if s.expired(entry, now) {
+		if now.UnixNano() >= entry.expiresAt+int64(s.stale) {
+			s.drop(key, ReasonExpired)
+			s.Policy.OnDel(key)
+			s.expiry.Cancel(key)
+			s.onMiss(key)
+			return val, false, false
+		}
+		refresh = true // stale
 	}
 
 	if s.defaultTTL != 0 {
 		// only ran on hits
 		budget := 4
-		for i := 0; i < budget; i++ {
-			if !s.expiry.HasExpired() {
-				break
-			}
-			victim := s.expiry.PopMin().K
-			if _, ok := s.Store[victim]; ok {
-				delete(s.Store, victim)
-				s.Policy.OnDel(victim)
+		s.sweep(now, budget)
+	}
+
+	if entry.refreshAt != 0 && now.UnixNano() >= entry.refreshAt {
+		refresh = true
+	}
+	s.hit(key, entry, now)
+	return entry.val, true, refresh
+}
+
+// SetExpiryIndex replaces the index used to find expired entries, the
+// entries in the shard are moved to the new index.
+func (s *Shard[K, V]) SetExpiryIndex(idx ttl_queue.ExpiryIndex[K]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx.Reset()
+	for k, e := range s.Store {
+		if e.expiresAt != 0 {
+			idx.Schedule(k, e.expiry().Add(s.stale))
+		}
+	}
+	s.expiry = idx
+}
+
+// SetPolicies gives shards[i] the policy ps[i], while all shards are locked
+// so the janitor and concurrent operations never see a policy change under
+// them. Returns false without changing anything if a shard holds entries.
+func SetPolicies[K comparable, V any](shards []*Shard[K, V], ps []policies.Policy[K]) bool {
+	for _, s := range shards {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	for _, s := range shards {
+		if len(s.Store) != 0 {
+			return false
+		}
+	}
+	for i, s := range shards {
+		s.Policy = ps[i]
+		s.meta = nil
+	}
+	return true
+}
+
+// SetRefresh configures refresh-ahead: entries set afterwards are due for a
+// refresh refreshAfter after they were set (0 disables it). Expired entries
+// are kept and served for another stale, so they can be refreshed in the
+// background instead of missing.
+func (s *Shard[K, V]) SetRefresh(refreshAfter, stale time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshAfter = max(refreshAfter, 0)
+	s.stale = max(stale, 0)
+}
+
+func (s *Shard[K, V]) refreshAt() int64 {
+	if s.refreshAfter == 0 {
+		return 0
+	}
+	return s.now().Add(s.refreshAfter).UnixNano()
+}
+
+// sweep removes up to limit expired entries, limit <= 0 removes all of
+// them. Returns the number of removed entries and the number of keys popped
+// from the index, which also includes keys that were already gone or didn't
+// expire after all. Must hold the lock.
+func (s *Shard[K, V]) sweep(now time.Time, limit int) (expired, popped int) {
+	s.popped = s.expiry.PopExpired(s.popped[:0], now, limit)
+	for _, victim := range s.popped {
+		entry, ok := s.Store[victim]
+		if !ok {
+			continue
+		}
+		// the index may be behind the entry, don't trust it blindly
+		if !s.expired(entry, now.Add(-s.stale)) {
+			if s.defaultTTL != 0 && entry.expiresAt != 0 {
+				s.schedule(victim, entry)
 			}
+			continue
 		}
+		s.drop(victim, ReasonExpired)
+		s.Policy.OnDel(victim)
+		expired++
 	}
+	return expired, len(s.popped)
+}
 
-	s.Policy.OnHit(key)
-	return entry.val, true
+// sample examines up to n entries, starting at a random position of the
+// store, and removes the expired ones. For shards without an expiry index.
+// Returns the number of removed and examined entries. Must hold the lock.
+func (s *Shard[K, V]) sample(now time.Time, n int) (expired, sampled int) {
+	for k, e := range s.Store {
+		if sampled == n {
+			break
+		}
+		sampled++
+		// kept for the stale window, like entries in an index
+		if s.expired(e, now.Add(-s.stale)) {
+			s.drop(k, ReasonExpired)
+			s.Policy.OnDel(k)
+			expired++
+		}
+	}
+	return expired, sampled
+}
+
+// expired reports whether entry expired at now. Without a default TTL
+// nothing expires.
+func (s *Shard[K, V]) expired(entry Entry[V], now time.Time) bool {
+	return s.defaultTTL != 0 && entry.expiresAt != 0 && entry.expiresAt <= now.UnixNano()
+}
+
+// schedule indexes key for removal once entry and the stale window expired,
+// persisted entries aren't indexed.
+func (s *Shard[K, V]) schedule(key K, entry Entry[V]) {
+	if entry.expiresAt == 0 {
+		s.expiry.Cancel(key)
+		return
+	}
+	s.expiry.Schedule(key, entry.expiry().Add(s.stale))
+}
+
+// overflows reports whether storing entry under key would exceed the
+// capacity, the cost budget or the memory budget of the shard.
+func (s *Shard[K, V]) overflows(key K, entry Entry[V]) bool {
+	old, exists := s.Store[key]
+	if !exists && s.cap > 0 && len(s.Store) >= s.cap {
+		return true
+	}
+	if s.maxCost > 0 && s.cost-old.cost+entry.cost > s.maxCost {
+		return true
+	}
+	return s.maxMemory > 0 && s.memory-old.size+entry.size > s.maxMemory
+}
+
+// drop removes key from the store and its cost from the total and queues
+// the removal for the listener, the policy isn't notified.
+func (s *Shard[K, V]) drop(key K, reason RemovalReason) (Entry[V], bool) {
+	e, ok := s.unstore(key)
+	if ok {
+		s.queueRemoval(key, e.val, reason)
+	}
+	return e, ok
+}
+
+// unstore is drop without telling the listener, must hold the lock.
+func (s *Shard[K, V]) unstore(key K) (Entry[V], bool) {
+	e, ok := s.Store[key]
+	if !ok {
+		return e, false
+	}
+	delete(s.Store, key)
+	if s.meta != nil {
+		delete(s.meta, key)
+	}
+	s.cost -= e.cost
+	s.memory -= e.size
+	return e, true
+}
+
+// failSet reports key as evicted when it made room for a set that failed
+// anyway, and returns the evictions. Must hold the lock.
+func (s *Shard[K, V]) failSet(key K, self Entry[V], selfEvicted bool, evicted int) int {
+	if !selfEvicted {
+		return evicted
+	}
+	s.queueRemoval(key, self.val, ReasonEvicted)
+	return evicted + 1
+}
+
+// weigh returns the cost of an entry, 1 when no weigher is set.
+func (s *Shard[K, V]) weigh(key K, val V) int64 {
+	if s.weigher == nil {
+		return 1
+	}
+	return max(s.weigher(key, val), 0)
+}
+
+// size estimates the bytes of key and val. Walking a value can take long,
+// so it runs before the write lock is taken, withOverhead adds the rest
+// under the lock. Returns 0 without a memory budget, so sizing costs
+// nothing unless it is used.
+func (s *Shard[K, V]) size(key K, val V) int64 {
+	s.mu.RLock()
+	maxMemory, f := s.maxMemory, s.sizer
+	s.mu.RUnlock()
+	if maxMemory == 0 {
+		return 0
+	}
+	return sizeOf(f, key, val)
+}
+
+// sizes is size for a batch, nil without a memory budget.
+func (s *Shard[K, V]) sizes(keys []K, vals []V) []int64 {
+	s.mu.RLock()
+	maxMemory, f := s.maxMemory, s.sizer
+	s.mu.RUnlock()
+	if maxMemory == 0 {
+		return nil
+	}
+	sizes := make([]int64, len(keys))
+	for i, key := range keys {
+		sizes[i] = sizeOf(f, key, vals[i])
+	}
+	return sizes
+}
+
+// measure is size for values that are only known under the lock, must hold
+// the lock.
+func (s *Shard[K, V]) measure(key K, val V) int64 {
+	if s.maxMemory == 0 {
+		return 0
+	}
+	return sizeOf(s.sizer, key, val)
+}
+
+func sizeOf[K comparable, V any](f func(V) int64, key K, val V) int64 {
+	var size int64
+	if f != nil {
+		size = max(f(val), 0)
+	} else {
+		size = sizer.Of(val) - sizer.Static[V]() // Entry[V] holds the static part
+	}
+	return size + sizer.Of(key) - sizer.Static[K]()
+}
+
+// withOverhead adds the overhead of the store, the expiry queue and the
+// policy to the size of a key and value. Returns 0 without a memory budget,
+// must hold the lock.
+func (s *Shard[K, V]) withOverhead(size int64) int64 {
+	if s.maxMemory == 0 {
+		return 0
+	}
+	size += sizer.MapEntry[K, Entry[V]]() + s.expiry.KeyOverhead()
+	if r, ok := s.Policy.(policies.MemoryReporter); ok {
+		size += r.KeyOverhead()
+	}
+	if _, ok := s.Policy.(policies.Sampler); ok {
+		size += sizer.MapEntry[K, policies.Sample]()
+	}
+	return size
+}
+
+// SetMaxMemory sets the memory budget of the shard in bytes, 0 means no
+// limit. Only entries set afterwards are measured.
+func (s *Shard[K, V]) SetMaxMemory(maxMemory int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxMemory = max(maxMemory, 0)
+}
+
+// SetSizer sets the function used to estimate the bytes of a value, nil
+// estimates them by walking the value (see sizer.Of).
+func (s *Shard[K, V]) SetSizer(f func(V) int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizer = f
+}
+
+// Memory returns the estimated bytes the entries of the shard take.
+func (s *Shard[K, V]) Memory() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.memory
+}
+
+// SetMaxCost sets the cost budget of the shard, 0 means no limit. Entries
+// are only evicted on the next set.
+func (s *Shard[K, V]) SetMaxCost(maxCost int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxCost = max(maxCost, 0)
+}
+
+// SetWeigher sets the function used to compute the cost of an entry, nil
+// gives every entry a cost of 1. Costs of stored entries aren't recomputed.
+func (s *Shard[K, V]) SetWeigher(w func(K, V) int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weigher = w
+}
+
+// Cost returns the total cost of the entries in the shard.
+func (s *Shard[K, V]) Cost() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cost
+}
+
+// evict asks the policy for a victim, or samples one from the store when the
+// policy is a sampling policy.
+func (s *Shard[K, V]) evict() (K, bool) {
+	sp, ok := s.Policy.(policies.Sampler)
+	if !ok {
+		return s.Policy.Evict()
+	}
+
+	var victim K
+	var worst policies.Sample
+	found := false
+	n := sp.Samples()
+	// map iteration starts at a random position, every resident key has
+	// metadata
+	for k, m := range s.meta {
+		if !found || sp.Less(m, worst, s.clock) {
+			victim, worst, found = k, m, true
+		}
+		n--
+		if n <= 0 {
+			break
+		}
+	}
+	return victim, found
+}
+
+// touch records an access in the metadata of key, only sampling policies
+// use it.
+func (s *Shard[K, V]) touch(key K) {
+	sp, ok := s.Policy.(policies.Sampler)
+	if !ok {
+		return
+	}
+	if s.meta == nil {
+		s.meta = make(map[K]policies.Sample)
+	}
+	s.clock++
+	s.meta[key] = sp.Touch(s.meta[key], s.clock)
+}
+
+// admit asks policies that filter admissions whether key may replace a
+// resident key.
+func (s *Shard[K, V]) admit(key K) bool {
+	if a, ok := s.Policy.(policies.Admitter[K]); ok {
+		return a.Admit(key)
+	}
+	return true
+}
+
+// onMiss reports a miss to policies that want to know about them.
+func (s *Shard[K, V]) onMiss(key K) {
+	if o, ok := s.Policy.(policies.MissObserver[K]); ok {
+		o.OnMiss(key)
+	}
 }
 
 // Peek is like get but won't affect eviction Policy.
@@ -143,23 +628,60 @@ func (s *Shard[K, V]) Peek(key K) (V, bool) {
 	return entry.val, true
 }
 
+// PeekLive is like Peek, but misses expired entries. It leaves them for
+// the janitor, so it only takes the read lock.
+func (s *Shard[K, V]) PeekLive(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.Store[key]
+	if !ok || s.expired(entry, s.now()) {
+		var zero V
+		return zero, false
+	}
+	return entry.val, true
+}
+
 func (s *Shard[K, V]) Del(key K) (success bool) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
-	if _, ok := s.Store[key]; !ok {
+	if _, ok := s.drop(key, ReasonDeleted); !ok {
 		return false
 	}
-	delete(s.Store, key)
 	s.Policy.OnDel(key)
 	return true
 }
 
+// DelMany deletes keys under a single lock and records in deleted[i]
+// whether keys[i] was found. Returns the number of deleted keys.
+func (s *Shard[K, V]) DelMany(keys []K, deleted []bool) (n int) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	for i, key := range keys {
+		if _, ok := s.drop(key, ReasonDeleted); ok {
+			s.Policy.OnDel(key)
+			deleted[i] = true
+			n++
+		}
+	}
+	return n
+}
+
 func (s *Shard[K, V]) Flush() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
+	if s.listener != nil {
+		for k, e := range s.Store {
+			s.queueRemoval(k, e.val, ReasonFlushed)
+		}
+	}
 	clear(s.Store)
+	clear(s.meta)
+	s.cost = 0
+	s.memory = 0
 	s.Policy.Reset()
 	s.expiry.Reset()
 }
@@ -175,16 +697,41 @@ func (s *Shard[K, V]) Equals(o *Shard[K, V]) bool {
 
 func (s *Shard[K, V]) setNow(now func() time.Time) {
 	s.now = now
-	s.expiry.SetNow(now)
+	if e, ok := s.expiry.(interface{ SetNow(func() time.Time) }); ok {
+		e.SetNow(now)
+	}
 }
 
 func (s *Shard[K, V]) Validate() error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.expiry.Len() < len(s.Store) {
+	var cost, memory int64
+	scheduled := 0
+	for _, e := range s.Store {
+		cost += e.cost
+		memory += e.size
+		if e.expiresAt != 0 {
+			scheduled++
+		}
+	}
+	_, lazy := s.expiry.(ttl_queue.NoIndex[K])
+	if !lazy && s.expiry.Len() < scheduled {
 		return errors.New("expiry out of sync")
 	}
+	if cost != s.cost {
+		return errors.New("cost out of sync")
+	}
+	if memory != s.memory {
+		return errors.New("memory out of sync")
+	}
+	// sampling policies keep no state, but the shard keeps their metadata
+	if _, ok := s.Policy.(policies.Sampler); ok {
+		if len(s.meta) != len(s.Store) {
+			return errors.New("sampling metadata out of sync")
+		}
+		return nil
+	}
 	if len(s.Store) != s.Policy.Len() {
 		return errors.New("policy out of sync")
 	}
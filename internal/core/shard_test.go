@@ -3,10 +3,17 @@ package core
 import (
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
+// queue returns the heap shards index their expiries with by default.
+func queue[K comparable, V any](s *Shard[K, V]) *ttl_queue.TTLQueue[K] {
+	return s.expiry.(*ttl_queue.TTLQueue[K])
+}
+
 func TestShard_InitShard(t *testing.T) {
 	s := InitShard[int, string](policies.NewFIFO[int](), 2, 100)
 	if ptype, _ := s.Policy.Type(); ptype != policies.TypeFIFO {
@@ -24,12 +31,12 @@ func TestShard_Set_TTL(t *testing.T) {
 	s.setNow(func() time.Time { return n })
 
 	s.Set(1, 1)
-	if e := s.Store[1]; !e.expiresAt.Equal(n.Add(100)) {
-		t.Errorf("expected %v + 100, got %v", n, e.expiresAt)
+	if e := s.Store[1]; !e.expiry().Equal(n.Add(100)) {
+		t.Errorf("expected %v + 100, got %v", n, e.expiry())
 	}
 	s.SetWithTTL(2, 2, 50)
-	if e := s.Store[2]; !e.expiresAt.Equal(n.Add(50)) {
-		t.Errorf("expected %v + 50, got %v", n, e.expiresAt)
+	if e := s.Store[2]; !e.expiry().Equal(n.Add(50)) {
+		t.Errorf("expected %v + 50, got %v", n, e.expiry())
 	}
 }
 
@@ -94,6 +101,26 @@ func TestShard_Set_Evict(t *testing.T) {
 	}
 }
 
+// Evicted keys leave the expiry index, it doesn't outgrow the store.
+func TestShard_Set_Evict_CancelsExpiry(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	for i := range 1000 {
+		s.Set(i, i)
+	}
+	if l := s.expiry.Len(); l != 10 {
+		t.Errorf("expected expiry len=10, got %d", l)
+	}
+
+	s = InitShard[int, int](policies.NewLRU[int](), 0, time.Minute)
+	s.SetMaxMemory(4096)
+	for i := range 1000 {
+		s.Set(i, i)
+	}
+	if l := s.expiry.Len(); l != len(s.Store) {
+		t.Errorf("expected expiry len=%d, got %d", len(s.Store), l)
+	}
+}
+
 func TestShard_Set_AttemptEvictNoVictim(t *testing.T) {
 	s := InitShard[int, string](policies.NewFIFO[int](), 1, 100)
 
@@ -239,13 +266,13 @@ func TestShard_Get_ExpiryBudget(t *testing.T) {
 
 	s.Get(5) // hit: should run cleanup
 
-	if !s.expiry.HasExpired() {
+	if !queue(s).HasExpired() {
 		t.Fatalf("expected true, got false")
 	}
 	if l := s.expiry.Len(); l != 2 { // 4, 5
 		t.Fatalf("expected len=2, got %d", l)
 	}
-	e, ok := s.expiry.Peek()
+	e, ok := queue(s).Peek()
 	if !ok {
 		t.Fatalf("expected true, got false")
 	}
@@ -263,7 +290,7 @@ func TestShard_Get_ExpiryBudget(t *testing.T) {
 	if l := s.expiry.Len(); l != 1 {
 		t.Fatalf("expected len=1, got %d", l)
 	}
-	e, ok = s.expiry.Peek()
+	e, ok = queue(s).Peek()
 	if !ok {
 		t.Fatalf("expected true, got false")
 	}
@@ -280,7 +307,7 @@ func TestShard_Get_ExpiryBudget(t *testing.T) {
 	if l := s.expiry.Len(); l != 1 {
 		t.Fatalf("expected len=1, got %d", l)
 	}
-	e, ok = s.expiry.Peek()
+	e, ok = queue(s).Peek()
 	if !ok {
 		t.Fatalf("expected true, got false")
 	}
@@ -299,13 +326,13 @@ func TestShard_Get_NoTTL(t *testing.T) {
 
 	s.Get(2) // hit, but DefaultTTL == 0: shouldn't run cleanup
 
-	if !s.expiry.HasExpired() {
+	if !queue(s).HasExpired() {
 		t.Fatalf("expected true, got false")
 	}
 	if l := s.expiry.Len(); l != 2 { // 1, 2
 		t.Fatalf("expected len=2, got %d", l)
 	}
-	e, ok := s.expiry.Peek()
+	e, ok := queue(s).Peek()
 	if !ok {
 		t.Fatalf("expected true, got false")
 	}
@@ -350,6 +377,23 @@ func TestShard_Peek_Miss(t *testing.T) {
 	}
 }
 
+func TestShard_PeekLive(t *testing.T) {
+	s := InitShard[int, string](policies.NewFIFO[int](), 2, time.Minute)
+	s.Set(1, "one")
+	s.SetWithTTL(2, "two", -50)
+
+	if ret, ok := s.PeekLive(1); !ok || ret != "one" {
+		t.Errorf("expected (one, true), got (%s, %v)", ret, ok)
+	}
+	if _, ok := s.PeekLive(2); ok {
+		t.Errorf("expected expired miss, got hit")
+	}
+	// left for the janitor
+	if _, ok := s.Store[2]; !ok {
+		t.Errorf("expected key=2 still stored")
+	}
+}
+
 func TestShard_Del(t *testing.T) {
 	s := InitShard[int, string](policies.NewFIFO[int](), 2, 100)
 
@@ -409,7 +453,7 @@ func TestShard_Flush(t *testing.T) {
 		t.Fatalf("expected zero value, got %v", victim)
 	}
 
-	if _, ok := s.expiry.Peek(); ok {
+	if _, ok := queue(s).Peek(); ok {
 		t.Fatalf("expected false, got true")
 	}
 }
@@ -442,3 +486,527 @@ func TestShard_setNow(t *testing.T) {
 		t.Errorf("now not set")
 	}
 }
+
+type missRecorder struct {
+	*policies.FIFO[int]
+	misses []int
+}
+
+func (p *missRecorder) OnMiss(k int) {
+	p.misses = append(p.misses, k)
+}
+
+func TestShard_Get_OnMiss(t *testing.T) {
+	p := &missRecorder{FIFO: policies.NewFIFO[int]()}
+	s := InitShard[int, int](p, 10, 100)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	s.Set(1, 1)
+	s.SetWithTTL(2, 2, -50)
+
+	s.Get(1) // hit
+	s.Get(2) // expired
+	s.Get(3) // miss
+
+	if len(p.misses) != 2 {
+		t.Fatalf("expected 2 misses, got %d", len(p.misses))
+	}
+	if p.misses[0] != 2 || p.misses[1] != 3 {
+		t.Errorf("expected misses [2 3], got %v", p.misses)
+	}
+}
+
+type rejectAll struct {
+	*policies.FIFO[int]
+}
+
+func (p *rejectAll) Admit(k int) bool {
+	return false
+}
+
+func TestShard_Set_AdmissionRejected(t *testing.T) {
+	s := InitShard[int, string](&rejectAll{FIFO: policies.NewFIFO[int]()}, 1, 100)
+
+	// not full, admission isn't consulted
+	if ok, _ := s.Set(1, "one"); !ok {
+		t.Fatalf("expected success, got false")
+	}
+	// full, key is rejected before anything is evicted
+	ok, evicted := s.Set(2, "two")
+	if ok {
+		t.Fatalf("expected failure, got true")
+	}
+	if evicted != 0 {
+		t.Fatalf("expected evicted=0, got %d", evicted)
+	}
+	if _, ok := s.Store[1]; !ok {
+		t.Errorf("expected key=1 found, got false")
+	}
+	// updates of resident keys are always accepted
+	if ok, _ := s.Set(1, "uno"); !ok {
+		t.Errorf("expected success, got false")
+	}
+}
+
+func TestShard_Set_SampledLRU(t *testing.T) {
+	// sampling at least the whole shard makes it exact
+	s := InitShard[int, int](policies.NewSampledLRU[int](10), 3, 0)
+	s.Set(1, 1)
+	s.Set(2, 2)
+	s.Set(3, 3)
+	s.Get(1)
+
+	ok, evicted := s.Set(4, 4)
+	if !ok || evicted != 1 {
+		t.Fatalf("expected (true, 1), got (%v, %d)", ok, evicted)
+	}
+	if _, ok := s.Store[2]; ok {
+		t.Errorf("expected key=2 evicted")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_Set_SampledLFU(t *testing.T) {
+	s := InitShard[int, int](policies.NewSampledLFU[int](10), 3, 0)
+	s.Set(1, 1)
+	s.Set(2, 2)
+	s.Set(3, 3)
+	s.Get(1)
+	s.Get(1)
+	s.Get(3)
+	s.Set(2, 20) // updates count as accesses
+
+	s.Set(4, 4)
+	if _, ok := s.Store[3]; ok {
+		t.Errorf("expected key=3 evicted")
+	}
+	if f := s.meta[1].Freq; f != 3 {
+		t.Errorf("expected freq=3, got %d", f)
+	}
+}
+
+func TestShard_SetWithCost(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 0, 0)
+	s.SetMaxCost(10)
+
+	// larger than the budget
+	if ok, _ := s.SetWithCost(1, 1, 11); ok {
+		t.Fatalf("expected failure, got true")
+	}
+	s.SetWithCost(1, 1, 4)
+	s.SetWithCost(2, 2, 4)
+	ok, evicted := s.SetWithCost(3, 3, 7)
+	if !ok || evicted != 2 {
+		t.Fatalf("expected (true, 2), got (%v, %d)", ok, evicted)
+	}
+	if c := s.Cost(); c != 7 {
+		t.Errorf("expected cost=7, got %d", c)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_SetWithCost_Update(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 0, 0)
+	s.SetMaxCost(10)
+	s.SetWithCost(1, 1, 5)
+	s.SetWithCost(2, 2, 5)
+
+	// growing key=2 evicts key=1
+	ok, evicted := s.SetWithCost(2, 20, 8)
+	if !ok || evicted != 1 {
+		t.Fatalf("expected (true, 1), got (%v, %d)", ok, evicted)
+	}
+	// growing key=2 again evicts key=2 itself, it is stored again after
+	ok, _ = s.SetWithCost(2, 200, 10)
+	if !ok {
+		t.Fatalf("expected success, got false")
+	}
+	if e := s.Store[2]; e.val != 200 || e.cost != 10 {
+		t.Errorf("expected (200, 10), got (%d, %d)", e.val, e.cost)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_SetWeigher(t *testing.T) {
+	s := InitShard[int, string](policies.NewFIFO[int](), 0, 0)
+	s.Set(1, "one")
+	if c := s.Cost(); c != 1 {
+		t.Errorf("expected cost=1, got %d", c)
+	}
+
+	s.SetWeigher(func(k int, v string) int64 { return int64(len(v)) })
+	s.Set(2, "three")
+	if c := s.Cost(); c != 6 {
+		t.Errorf("expected cost=6, got %d", c)
+	}
+
+	s.Del(2)
+	if c := s.Cost(); c != 1 {
+		t.Errorf("expected cost=1, got %d", c)
+	}
+}
+
+func TestShard_MaxMemory(t *testing.T) {
+	s := InitShard[int, string](policies.NewLRU[int](), 0, 0)
+	// not measured without a budget
+	s.Set(1, "one")
+	if m := s.Memory(); m != 0 {
+		t.Fatalf("expected memory=0, got %d", m)
+	}
+	s.Flush()
+
+	s.SetMaxMemory(4096)
+	s.Set(1, "a")
+	small := s.Memory()
+	if small <= 0 {
+		t.Fatalf("expected memory > 0, got %d", small)
+	}
+	s.Del(1)
+
+	big := make([]byte, 1000)
+	for i := range 10 {
+		s.Set(i, string(big))
+	}
+	if m := s.Memory(); m > 4096 {
+		t.Errorf("expected memory <= 4096, got %d", m)
+	}
+	if l := len(s.Store); l != 3 {
+		t.Errorf("expected 3 entries, got %d", l)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+
+	// larger than the budget
+	if ok, _ := s.Set(10, string(make([]byte, 5000))); ok {
+		t.Errorf("expected failure, got true")
+	}
+}
+
+func TestShard_SetSizer(t *testing.T) {
+	s := InitShard[int, string](policies.NewFIFO[int](), 0, 0)
+	s.SetMaxMemory(1000)
+	s.Set(1, "one")
+	overhead := s.Memory() - 3
+
+	s.SetSizer(func(v string) int64 { return 100 })
+	s.Set(2, "two")
+	if m := s.Memory(); m != overhead+3+overhead+100 {
+		t.Errorf("expected memory=%d, got %d", 2*overhead+103, m)
+	}
+}
+
+// Values are measured before the write lock is taken.
+func TestShard_SetSizer_Unlocked(t *testing.T) {
+	s := InitShard[int, string](policies.NewFIFO[int](), 0, 0)
+	s.SetMaxMemory(1000)
+	calls := 0
+	s.SetSizer(func(v string) int64 {
+		calls++
+		if !s.mu.TryLock() {
+			t.Errorf("expected shard to be unlocked")
+			return 0
+		}
+		s.mu.Unlock()
+		return 1
+	})
+	s.Set(1, "one")
+	s.SetWithTTL(2, "two", time.Minute)
+	s.SetMany([]int{3, 4}, []string{"three", "four"}, make([]bool, 2))
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+	if l := len(s.Store); l != 4 {
+		t.Errorf("expected len=4, got %d", l)
+	}
+}
+
+func TestShard_GetRefresh(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetRefresh(10*time.Second, 0)
+
+	s.Set(1, 1)
+	if _, hit, refresh := s.GetRefresh(1); !hit || refresh {
+		t.Fatalf("expected (true, false), got (%v, %v)", hit, refresh)
+	}
+
+	n = n.Add(10 * time.Second)
+	if v, hit, refresh := s.GetRefresh(1); !hit || !refresh || v != 1 {
+		t.Fatalf("expected (1, true, true), got (%d, %v, %v)", v, hit, refresh)
+	}
+
+	// a new value resets the deadline
+	s.Set(1, 2)
+	if _, _, refresh := s.GetRefresh(1); refresh {
+		t.Errorf("expected refresh=false, got true")
+	}
+}
+
+func TestShard_GetRefresh_Stale(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetRefresh(0, 30*time.Second)
+
+	s.SetWithTTL(1, 1, 10*time.Second)
+	if e, _ := queue(s).Peek(); !e.ExpiresAt.Equal(n.Add(40 * time.Second)) {
+		t.Errorf("expected expiry queued after the stale window, got %v", e.ExpiresAt.Sub(n))
+	}
+
+	// expired, served stale
+	n = n.Add(20 * time.Second)
+	if v, hit, refresh := s.GetRefresh(1); !hit || !refresh || v != 1 {
+		t.Fatalf("expected (1, true, true), got (%d, %v, %v)", v, hit, refresh)
+	}
+
+	// stale window over
+	n = n.Add(20 * time.Second)
+	if _, hit, _ := s.GetRefresh(1); hit {
+		t.Errorf("expected miss, got hit")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_GetMany(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetRefresh(time.Second, 0)
+	s.Set(1, 10)
+	s.SetWithTTL(2, 20, -time.Second) // expired
+	n = n.Add(time.Second)
+	s.Set(3, 30)
+
+	out := make(map[int]int)
+	hits, refresh := s.GetMany([]int{1, 2, 3, 4}, out)
+	if hits != 2 {
+		t.Errorf("expected hits=2, got %d", hits)
+	}
+	if len(out) != 2 || out[1] != 10 || out[3] != 30 {
+		t.Errorf("expected {1:10 3:30}, got %v", out)
+	}
+	if len(refresh) != 1 || refresh[0] != 1 {
+		t.Errorf("expected refresh [1], got %v", refresh)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_SetMany(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 2, 100)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+
+	ok := make([]bool, 3)
+	evicted := s.SetMany([]int{1, 2, 3}, []int{10, 20, 30}, ok)
+	if evicted != 1 {
+		t.Errorf("expected evicted=1, got %d", evicted)
+	}
+	if !ok[0] || !ok[1] || !ok[2] {
+		t.Errorf("expected all stored, got %v", ok)
+	}
+	if e := s.Store[3]; !e.expiry().Equal(n.Add(100)) {
+		t.Errorf("expected default ttl, got %v", e.expiry().Sub(n))
+	}
+
+	s.SetManyWithTTL([]int{4}, []int{40}, 50, ok[:1])
+	if e := s.Store[4]; !e.expiry().Equal(n.Add(50)) {
+		t.Errorf("expected ttl=50, got %v", e.expiry().Sub(n))
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_DelMany(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, 100)
+	s.Set(1, 1)
+	s.Set(2, 2)
+
+	deleted := make([]bool, 3)
+	if n := s.DelMany([]int{1, 3, 2}, deleted); n != 2 {
+		t.Errorf("expected n=2, got %d", n)
+	}
+	if !deleted[0] || deleted[1] || !deleted[2] {
+		t.Errorf("expected [true false true], got %v", deleted)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_SetExpiryIndex(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetWithTTL(1, 1, time.Second)
+	s.SetWithTTL(2, 2, time.Hour)
+	s.Set(3, 3)
+	s.Persist(3)
+
+	s.SetExpiryIndex(ttl_queue.NewTimingWheel[int](time.Millisecond))
+	if l := s.expiry.Len(); l != 2 {
+		t.Errorf("expected 2 keys moved, got %d", l)
+	}
+
+	n = n.Add(2 * time.Second)
+	s.Get(2) // hit: removes expired entries
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 removed")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+func TestShard_NoIndex(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.SetExpiryIndex(ttl_queue.NoIndex[int]{})
+
+	s.SetWithTTL(1, 1, time.Second)
+	s.SetWithTTL(2, 2, time.Hour)
+	n = n.Add(2 * time.Second)
+
+	s.Get(2) // hit: nothing indexed to clean up
+	if _, ok := s.Store[1]; !ok {
+		t.Errorf("expected key=1 to stay until it is read")
+	}
+	if _, hit := s.Get(1); hit {
+		t.Errorf("expected miss for an expired key")
+	}
+	if _, ok := s.Store[1]; ok {
+		t.Errorf("expected key=1 removed once read")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
+
+// Sampling metadata is only kept for sampling policies.
+func TestShard_SamplingMetadata(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, 0)
+	s.Set(1, 1)
+	s.Get(1)
+	if s.meta != nil {
+		t.Errorf("expected no metadata for FIFO, got %v", s.meta)
+	}
+
+	s = InitShard[int, int](policies.NewSampledLRU[int](0), 4, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	// every way a key leaves the store drops its metadata
+	inSync := func(step string) {
+		t.Helper()
+		if len(s.meta) != len(s.Store) {
+			t.Errorf("%s: expected metadata for %d keys, got %d", step, len(s.Store), len(s.meta))
+		}
+		for k := range s.meta {
+			if _, ok := s.Store[k]; !ok {
+				t.Errorf("%s: metadata for missing key=%d", step, k)
+			}
+		}
+		if err := s.Validate(); err != nil {
+			t.Errorf("%s: shard not valid: %v", step, err)
+		}
+	}
+	for i := range 4 {
+		s.Set(i, i)
+	}
+	s.Set(4, 4)
+	inSync("evict")
+	s.Del(4)
+	inSync("del")
+	s.SetWithTTL(5, 5, -50)
+	s.Get(5)
+	inSync("expire on get")
+	s.SetWithTTL(6, 6, -50)
+	s.sweep(n, 0)
+	if _, ok := s.Store[6]; ok {
+		t.Errorf("expected key=6 swept")
+	}
+	inSync("sweep")
+	s.Set(7, 7)
+	s.Set(7, 8)
+	inSync("replace")
+	s.Flush()
+	inSync("flush")
+	if len(s.meta) != 0 {
+		t.Errorf("expected no metadata after flush, got %d", len(s.meta))
+	}
+}
+
+// Entries only hold what every cache needs, optional state is kept elsewhere.
+func TestEntry_Size(t *testing.T) {
+	// value, expiry, refresh, cost, size, version, sliding
+	if size := unsafe.Sizeof(Entry[int]{}); size != 56 {
+		t.Errorf("expected 56 bytes, got %d", size)
+	}
+}
+
+// A rejected write leaves the old entry and its deadline in place.
+func TestShard_RejectedSet_KeepsExpiry(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 0, 5*time.Minute)
+	start := time.Now()
+	n := start
+	s.setNow(func() time.Time { return n })
+	s.SetMaxCost(10)
+	s.SetWithCost(1, 1, 1)
+	s.SetWeigher(func(int, int) int64 { return 100 })
+
+	if ok, _ := s.SetWithTTL(1, 2, time.Millisecond); ok {
+		t.Fatalf("expected set to be rejected")
+	}
+	n = n.Add(time.Second)
+	s.mu.Lock()
+	expired, _ := s.sweep(n, 0)
+	s.unlock()
+	if expired != 0 {
+		t.Errorf("expected nothing expired, got %d", expired)
+	}
+	if e, ok := s.Store[1]; !ok || e.val != 1 {
+		t.Errorf("expected the old entry to stay, got (%v, %v)", e.val, ok)
+	}
+	if at, _ := s.expiry.NextDeadline(); !at.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("expected the old deadline, got %v", at.Sub(start))
+	}
+}
+
+// Sweeps only remove entries that expired, whatever the index says.
+func TestShard_Sweep_NotExpired(t *testing.T) {
+	s := InitShard[int, int](policies.NewFIFO[int](), 10, time.Minute)
+	n := time.Now()
+	s.setNow(func() time.Time { return n })
+	s.Set(1, 1)
+	s.expiry.Schedule(1, n.Add(-time.Second)) // out of sync
+
+	s.mu.Lock()
+	expired, popped := s.sweep(n, 0)
+	s.unlock()
+	if expired != 0 || popped != 1 {
+		t.Errorf("expected (0, 1), got (%d, %d)", expired, popped)
+	}
+	if _, ok := s.Store[1]; !ok {
+		t.Errorf("expected key=1 to stay")
+	}
+	// indexed again at its real deadline
+	if at, ok := s.expiry.NextDeadline(); !ok || !at.Equal(n.Add(time.Minute)) {
+		t.Errorf("expected deadline in 1m, got %v", at.Sub(n))
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+// GetWithVersion is like GetRefresh, but also returns the version of the
+// entry. Versions start at 1 and increase on every write to the shard, so a
+// key never gets the same version twice.
+func (s *Shard[K, V]) GetWithVersion(key K) (val V, version uint64, hit bool, refresh bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	val, hit, refresh = s.get(key, s.now())
+	if !hit {
+		return val, 0, false, false
+	}
+	return val, s.Store[key].version, true, refresh
+}
+
+// SetIfVersion stores val with the default TTL if the version of key still
+// equals version. Returns the version of key afterwards, 0 when it is
+// missing, and whether val was stored.
+func (s *Shard[K, V]) SetIfVersion(key K, val V, version uint64) (current uint64, stored bool, evicted int) {
+	size := s.size(key, val)
+	s.mu.Lock()
+	defer s.unlock()
+
+	entry, ok := s.live(key, s.now())
+	if !ok {
+		return 0, false, 0
+	}
+	if entry.version != version {
+		return entry.version, false, 0
+	}
+	if stored, evicted = s.put(key, val, size, s.defaultTTL); !stored {
+		return s.Store[key].version, false, evicted
+	}
+	return s.version, true, evicted
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/policies"
+)
+
+func TestShard_GetWithVersion(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+
+	if _, v, hit, _ := s.GetWithVersion(1); hit || v != 0 {
+		t.Errorf("expected (0, false), got (%d, %v)", v, hit)
+	}
+	s.Set(1, 1)
+	_, v1, _, _ := s.GetWithVersion(1)
+	s.Set(2, 2)
+	s.Set(1, 1)
+	_, v2, _, _ := s.GetWithVersion(1)
+	if v1 == 0 || v2 <= v1 {
+		t.Errorf("expected increasing versions, got %d then %d", v1, v2)
+	}
+
+	// reads don't change the version
+	if _, v, _, _ := s.GetWithVersion(1); v != v2 {
+		t.Errorf("expected version=%d, got %d", v2, v)
+	}
+
+	// a deleted and recreated key gets a new version
+	s.Del(1)
+	s.Set(1, 1)
+	if _, v, _, _ := s.GetWithVersion(1); v <= v2 {
+		t.Errorf("expected version >%d, got %d", v2, v)
+	}
+}
+
+func TestShard_SetIfVersion(t *testing.T) {
+	s := InitShard[int, int](policies.NewLRU[int](), 10, time.Minute)
+
+	if current, stored, _ := s.SetIfVersion(1, 1, 1); stored || current != 0 {
+		t.Errorf("expected (0, false) on a miss, got (%d, %v)", current, stored)
+	}
+
+	s.Set(1, 1)
+	_, v, _, _ := s.GetWithVersion(1)
+	current, stored, _ := s.SetIfVersion(1, 2, v)
+	if !stored || current <= v {
+		t.Errorf("expected (>%d, true), got (%d, %v)", v, current, stored)
+	}
+	if _, stored, _ := s.SetIfVersion(1, 3, v); stored {
+		t.Errorf("expected stale version to be rejected")
+	}
+	if val, _ := s.Get(1); val != 2 {
+		t.Errorf("expected 2, got %d", val)
+	}
+
+	// Incr-style updates also change the version
+	s.Update(1, func(old int) int { return old + 1 })
+	if _, stored, _ := s.SetIfVersion(1, 3, current); stored {
+		t.Errorf("expected version to change on Update")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("shard not valid: %v", err)
+	}
+}
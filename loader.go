@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Loader loads the value of key on a miss. It returns the value and its TTL,
+// ttl <= 0 uses the default TTL.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (val V, ttl time.Duration, err error)
+
+// call is a load in flight, waiters block on done.
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+type failedLoad struct {
+	err       error
+	expiresAt time.Time
+}
+
+// flightGroup deduplicates loads for the keys of one shard and remembers
+// failed loads when errors are cached.
+type flightGroup[K comparable, V any] struct {
+	mu     sync.Mutex
+	calls  map[K]*call[V]
+	failed map[K]failedLoad
+}
+
+func newFlightGroup[K comparable, V any]() *flightGroup[K, V] {
+	return &flightGroup[K, V]{
+		calls:  make(map[K]*call[V]),
+		failed: make(map[K]failedLoad),
+	}
+}
+
+// finish removes the call of key from g, remembers its error when errors
+// are cached and releases its waiters.
+func (c *Cache[K, V]) finish(g *flightGroup[K, V], key K, cl *call[V]) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	if c.opts.ErrorTTL > 0 && cacheable(cl.err) {
+		now := time.Now()
+		g.pruneFailed(now)
+		g.failed[key] = failedLoad{cl.err, now.Add(c.opts.ErrorTTL)}
+	}
+	g.mu.Unlock()
+	close(cl.done)
+}
+
+// cacheable reports whether err may be cached for ErrorTTL. Missing keys
+// and cancelled loads aren't failures of the backend: the next caller, with
+// its own ctx, should load again.
+func cacheable(err error) bool {
+	return err != nil &&
+		!errors.Is(err, ErrNotFound) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// pruneFailed drops a few expired errors, so keys that are never requested
+// again don't pile up. Must hold g.mu.
+func (g *flightGroup[K, V]) pruneFailed(now time.Time) {
+	budget := 4
+	for k, f := range g.failed {
+		if !now.Before(f.expiresAt) {
+			delete(g.failed, k)
+		}
+		budget--
+		if budget == 0 {
+			return
+		}
+	}
+}
+
+var errLoaderPanicked = errors.New("loader panicked")
+
+// SetLoader sets the loader used to refresh entries in the background, see
+// Options.RefreshAfter and Options.StaleTTL. It is also used by GetOrLoad
+// when no loader is passed.
+func (c *Cache[K, V]) SetLoader(loader Loader[K, V]) {
+	c.loader = loader
+}
+
+// GetOrLoad returns the value of key, calling loader on a miss and storing
+// its result. Concurrent callers for the same key share a single call of
+// loader and wait for its result, or until their ctx is done. The loader
+// runs with the ctx of the caller that started it.
+// Errors are returned to every waiting caller but not stored, unless
+// Options.ErrorTTL is set, in which case the error is returned for that long
+// without calling loader again. Context errors are never stored.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	if c.closed() {
+		var zero V
+		return zero, ErrClosed
+	}
+	if val, hit := c.Get(key); hit {
+		return val, nil
+	}
+	if loader == nil {
+		loader = c.loader
+	}
+	if loader == nil {
+		var zero V
+		return zero, errors.New("loader must not be nil")
+	}
+
+	shard, idx := c.shardFor(key)
+	g := c.flights[idx]
+	g.mu.Lock()
+	if f, ok := g.failed[key]; ok {
+		if time.Now().Before(f.expiresAt) {
+			g.mu.Unlock()
+			var zero V
+			return zero, f.err
+		}
+		delete(g.failed, key)
+	}
+	cl, ok := g.calls[key]
+	if !ok {
+		// a load may have finished since the miss above, peek so the
+		// policy doesn't see the key twice
+		if val, hit := shard.PeekLive(key); hit {
+			g.mu.Unlock()
+			return val, nil
+		}
+		cl = &call[V]{done: make(chan struct{})}
+		g.calls[key] = cl
+		g.mu.Unlock()
+		c.load(ctx, g, key, cl, loader)
+		return cl.val, cl.err
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.val, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// refresh reloads key in the background, unless it is already loading or
+// its last load failed less than ErrorTTL ago. The current value is served
+// until the load succeeds.
+func (c *Cache[K, V]) refresh(key K) {
+	// Shutdown waits for the loads started before it closed the cache
+	c.lifecycle.RLock()
+	defer c.lifecycle.RUnlock()
+	if c.closed() {
+		return
+	}
+
+	_, idx := c.shardFor(key)
+	g := c.flights[idx]
+	g.mu.Lock()
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return
+	}
+	if f, ok := g.failed[key]; ok && time.Now().Before(f.expiresAt) {
+		g.mu.Unlock()
+		return
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	g.calls[key] = cl
+	g.mu.Unlock()
+
+	c.loads.Add(1)
+	go func() {
+		defer c.loads.Done()
+		c.load(c.loadCtx, g, key, cl, c.loader)
+	}()
+}
+
+// load calls loader, stores the result and releases the waiters of cl.
+func (c *Cache[K, V]) load(ctx context.Context, g *flightGroup[K, V], key K, cl *call[V], loader Loader[K, V]) {
+	cl.err = errLoaderPanicked // overwritten unless loader panics
+	defer c.finish(g, key, cl)
+
+	val, ttl, err := loader(ctx, key)
+	cl.val, cl.err = val, err
+	if err != nil {
+		return
+	}
+	if ttl > 0 {
+		c.SetWithTTL(key, val, ttl)
+	} else {
+		c.Set(key, val)
+	}
+}
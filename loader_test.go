@@ -0,0 +1,368 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/policies"
+)
+
+// waitLoaded waits until no load of key is in flight.
+func waitLoaded[K comparable, V any](t *testing.T, c *Cache[K, V], key K) {
+	t.Helper()
+	_, idx := c.shardFor(key)
+	g := c.flights[idx]
+	for range 1000 {
+		g.mu.Lock()
+		_, loading := g.calls[key]
+		g.mu.Unlock()
+		if !loading {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("load still in flight")
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var calls atomic.Int32
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		return k * 10, 0, nil
+	}
+
+	v, err := c.GetOrLoad(context.Background(), 1, loader)
+	if err != nil || v != 10 {
+		t.Fatalf("expected (10, nil), got (%d, %v)", v, err)
+	}
+	// stored
+	v, err = c.GetOrLoad(context.Background(), 1, loader)
+	if err != nil || v != 10 {
+		t.Fatalf("expected (10, nil), got (%d, %v)", v, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+
+	if _, err := c.GetOrLoad(context.Background(), 2, nil); err == nil {
+		t.Errorf("expected error for nil loader, got nil")
+	}
+}
+
+// missCounter counts the misses its policy sees.
+type missCounter struct {
+	policies.Policy[int]
+	misses, hits int
+}
+
+func (p *missCounter) OnMiss(int)  { p.misses++ }
+func (p *missCounter) OnHit(k int) { p.hits++; p.Policy.OnHit(k) }
+
+// The policy sees a miss once, not again when GetOrLoad checks for a load
+// that finished in the meantime.
+func TestCache_GetOrLoad_PolicyHooks(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := &missCounter{Policy: c.shards[0].Policy}
+	c.shards[0].Policy = p
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		return k, 0, nil
+	}
+
+	if _, err := c.GetOrLoad(context.Background(), 1, loader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.misses != 1 || p.hits != 0 {
+		t.Errorf("expected 1 miss and 0 hits, got %d and %d", p.misses, p.hits)
+	}
+}
+
+func TestCache_GetOrLoad_TTL(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetNumShards(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		return k, -time.Second, nil
+	}
+	c.GetOrLoad(context.Background(), 1, loader)
+	if _, ok := c.Peek(1); !ok {
+		t.Errorf("expected ttl <= 0 to use the default TTL")
+	}
+
+	loader = func(ctx context.Context, k int) (int, time.Duration, error) {
+		return k, time.Nanosecond, nil
+	}
+	c.GetOrLoad(context.Background(), 2, loader)
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get(2); ok {
+		t.Errorf("expected key=2 expired")
+	}
+}
+
+func TestCache_GetOrLoad_Singleflight(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return 42, 0, nil
+	}
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), 1, loader)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = v
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+	for i, v := range results {
+		if v != 42 {
+			t.Errorf("caller %d: expected 42, got %d", i, v)
+		}
+	}
+}
+
+func TestCache_GetOrLoad_Error(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errLoad := errors.New("load failed")
+	var calls atomic.Int32
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		return 0, 0, errLoad
+	}
+
+	for range 2 {
+		if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("expected errLoad, got %v", err)
+		}
+	}
+	// not cached
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected len=0, got %d", c.Len())
+	}
+}
+
+func TestCache_GetOrLoad_ErrorTTL(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetErrorTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errLoad := errors.New("load failed")
+	var calls atomic.Int32
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		return 0, 0, errLoad
+	}
+
+	for range 3 {
+		if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("expected errLoad, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 call, got %d", n)
+	}
+
+	// expired errors are retried
+	_, idx := c.shardFor(1)
+	g := c.flights[idx]
+	g.mu.Lock()
+	g.failed[1] = failedLoad{errLoad, time.Now().Add(-time.Second)}
+	g.mu.Unlock()
+	c.GetOrLoad(context.Background(), 1, loader)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+}
+
+// A load cancelled by the ctx of its caller doesn't fail the next caller.
+func TestCache_GetOrLoad_ErrorTTL_Context(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().SetErrorTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var calls atomic.Int32
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		return k, 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetOrLoad(ctx, 1, loader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if v, err := c.GetOrLoad(context.Background(), 1, loader); err != nil || v != 1 {
+		t.Errorf("expected (1, nil), got (%d, %v)", v, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+}
+
+func TestCache_GetOrLoad_ContextDone(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	loader := func(ctx context.Context, k int) (int, time.Duration, error) {
+		close(started)
+		<-release
+		return 1, 0, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.GetOrLoad(context.Background(), 1, loader)
+		close(done)
+	}()
+	<-started
+
+	// waiter gives up, the load continues
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetOrLoad(ctx, 1, loader); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	close(release)
+	<-done
+	if v, ok := c.Peek(1); !ok || v != 1 {
+		t.Errorf("expected (1, true), got (%d, %v)", v, ok)
+	}
+}
+
+func TestCache_GetOrLoad_Panic(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		c.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, time.Duration, error) {
+			panic("boom")
+		})
+	}()
+
+	// the call was cleaned up, the next load runs
+	v, err := c.GetOrLoad(context.Background(), 1, func(ctx context.Context, k int) (int, time.Duration, error) {
+		return 1, 0, nil
+	})
+	if err != nil || v != 1 {
+		t.Errorf("expected (1, nil), got (%d, %v)", v, err)
+	}
+}
+
+func TestCache_RefreshAhead(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetRefreshAfter(10 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var calls atomic.Int32
+	loaded := make(chan struct{}, 10)
+	c.SetLoader(func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		defer func() { loaded <- struct{}{} }()
+		return 2, 0, nil
+	})
+
+	c.Set(1, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	// current value is returned right away, reload runs once
+	for range 10 {
+		if v, ok := c.Get(1); !ok || (v != 1 && v != 2) {
+			t.Fatalf("expected hit, got (%d, %v)", v, ok)
+		}
+	}
+	select {
+	case <-loaded:
+	case <-time.After(time.Second):
+		t.Fatal("expected background reload")
+	}
+	waitLoaded(t, c, 1)
+	if v, _ := c.Peek(1); v != 2 {
+		t.Errorf("expected refreshed value 2, got %d", v)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 reload, got %d", n)
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	c, err := NewCache[int, int](NewOptions[int]().
+		SetStaleTTL(time.Hour).
+		SetErrorTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var calls atomic.Int32
+	loaded := make(chan struct{}, 10)
+	c.SetLoader(func(ctx context.Context, k int) (int, time.Duration, error) {
+		calls.Add(1)
+		defer func() { loaded <- struct{}{} }()
+		return 0, 0, errors.New("backend down")
+	})
+
+	c.SetWithTTL(1, 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// expired but served while the backend fails
+	if v, ok := c.Get(1); !ok || v != 1 {
+		t.Fatalf("expected stale (1, true), got (%d, %v)", v, ok)
+	}
+	<-loaded
+	waitLoaded(t, c, 1)
+	if v, ok := c.Get(1); !ok || v != 1 {
+		t.Fatalf("expected stale (1, true), got (%d, %v)", v, ok)
+	}
+	// failed reloads are not retried within ErrorTTL
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 reload, got %d", n)
+	}
+}
@@ -3,16 +3,32 @@ package cache
 import (
 	"time"
 
+	"github.com/jeltjongsma/go-cache/internal/core"
 	"github.com/jeltjongsma/go-cache/pkg/hasher"
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 type Options[K comparable] struct {
-	Capacity   int
-	Policy     policies.PolicyType
-	NumShards  int
-	Hasher     *hasher.Hasher[K]
-	DefaultTTL time.Duration
+	Capacity      int
+	MaxCost       int64
+	MaxMemory     int64
+	Policy        policies.PolicyType
+	PolicyFactory policies.PolicyFactory[K]
+	NumShards     int
+	Hasher        *hasher.Hasher[K]
+	DefaultTTL    time.Duration
+	ErrorTTL      time.Duration
+	RefreshAfter  time.Duration
+	StaleTTL      time.Duration
+	ExpiryIndex   ttl_queue.IndexType
+	// background removal of expired entries, see SetJanitorInterval
+	JanitorInterval     time.Duration
+	JanitorBudget       int
+	JanitorMaxLockHold  time.Duration
+	JanitorSweepMode    SweepMode
+	JanitorBatchSize    int
+	JanitorExpiredRatio float64
 }
 
 // Options configures a cache instance. All setters return *Options, so they
@@ -23,11 +39,17 @@ type Options[K comparable] struct {
 //	    SetCapacity(1000)
 func NewOptions[K comparable]() *Options[K] {
 	return &Options[K]{
-		Capacity:   1000,
-		Policy:     policies.TypeFIFO,
-		NumShards:  16,
-		Hasher:     hasher.NewHasher[K](nil),
-		DefaultTTL: 5 * time.Minute,
+		Capacity:    1000,
+		Policy:      policies.TypeFIFO,
+		NumShards:   16,
+		Hasher:      hasher.NewHasher[K](nil),
+		DefaultTTL:  5 * time.Minute,
+		ExpiryIndex: ttl_queue.TypeHeap,
+
+		JanitorInterval:     time.Second,
+		JanitorMaxLockHold:  time.Millisecond,
+		JanitorBatchSize:    core.DefaultSweepBatch,
+		JanitorExpiredRatio: core.DefaultExpiredRatio,
 	}
 }
 
@@ -36,11 +58,34 @@ func (o *Options[K]) SetCapacity(c int) *Options[K] {
 	return o
 }
 
+// SetMaxCost sets the total cost budget, split evenly over the shards.
+// Set to 0 for no limit. See Cache.SetWithCost and Cache.SetWeigher.
+func (o *Options[K]) SetMaxCost(c int64) *Options[K] {
+	o.MaxCost = c
+	return o
+}
+
+// SetMaxMemory sets the memory budget in bytes, split evenly over the
+// shards. Entries are estimated as key + value + the overhead of the store,
+// the expiry queue and the policy. Set to 0 for no limit.
+// See Cache.SetSizer.
+func (o *Options[K]) SetMaxMemory(b int64) *Options[K] {
+	o.MaxMemory = b
+	return o
+}
+
 func (o *Options[K]) SetPolicy(p policies.PolicyType) *Options[K] {
 	o.Policy = p
 	return o
 }
 
+// SetPolicyFactory sets a custom policy, it overrides Policy. The factory is
+// called once per shard, so every shard gets its own instance.
+func (o *Options[K]) SetPolicyFactory(f policies.PolicyFactory[K]) *Options[K] {
+	o.PolicyFactory = f
+	return o
+}
+
 func (o *Options[K]) SetNumShards(n int) *Options[K] {
 	o.NumShards = n
 	return o
@@ -55,3 +100,91 @@ func (o *Options[K]) SetDefaultTTL(ttl time.Duration) *Options[K] {
 	o.DefaultTTL = ttl
 	return o
 }
+
+// SetErrorTTL sets how long GetOrLoad keeps returning a loader error before
+// calling the loader again. Set to 0 to not cache errors (default). ErrNotFound
+// and context errors are never cached.
+func (o *Options[K]) SetErrorTTL(ttl time.Duration) *Options[K] {
+	o.ErrorTTL = ttl
+	return o
+}
+
+// SetRefreshAfter enables refresh-ahead: a Get more than d after an entry was
+// set still returns the value, and reloads it in the background through the
+// loader set with Cache.SetLoader. Set to 0 to disable (default).
+func (o *Options[K]) SetRefreshAfter(d time.Duration) *Options[K] {
+	o.RefreshAfter = d
+	return o
+}
+
+// SetStaleTTL keeps expired entries for another ttl, during which a Get
+// returns the stale value and reloads it in the background, also while the
+// loader keeps failing. Set to 0 to disable (default).
+func (o *Options[K]) SetStaleTTL(ttl time.Duration) *Options[K] {
+	o.StaleTTL = ttl
+	return o
+}
+
+// SetExpiryIndex sets how shards find expired entries: ttl_queue.TypeHeap
+// (default) keeps them ordered by expiry, ttl_queue.TypeTimingWheel schedules
+// and cancels in O(1) but expires up to one tick (ttl_queue.DefaultTick)
+// late, and ttl_queue.TypeNone only notices expired entries when they are
+// read. Expired entries are never returned by Get either way.
+// Without a DefaultTTL nothing expires and no index is used.
+func (o *Options[K]) SetExpiryIndex(t ttl_queue.IndexType) *Options[K] {
+	o.ExpiryIndex = t
+	return o
+}
+
+// SetJanitorInterval sets the longest a shard goes without the janitor
+// removing its expired entries. Shards are swept earlier when they know an
+// entry expires earlier. A single janitor goroutine serves all shards, it
+// only runs with a DefaultTTL. Values <= 0 use 1s.
+func (o *Options[K]) SetJanitorInterval(d time.Duration) *Options[K] {
+	o.JanitorInterval = d
+	return o
+}
+
+// SetJanitorBudget sets the maximum number of expired entries the janitor
+// removes from a shard at once (or samples, see SetJanitorSweepMode), the
+// rest follows after the other shards were swept. Set to 0 for no limit
+// (default).
+func (o *Options[K]) SetJanitorBudget(n int) *Options[K] {
+	o.JanitorBudget = n
+	return o
+}
+
+// SetJanitorMaxLockHold sets the maximum time the janitor holds the lock of
+// a shard, and with it blocks reads and writes to the shard. It is checked
+// every 64 entries. Set to 0 for no limit, defaults to 1ms. Doesn't apply to
+// SweepIncremental, which holds the lock for a batch at a time.
+func (o *Options[K]) SetJanitorMaxLockHold(d time.Duration) *Options[K] {
+	o.JanitorMaxLockHold = d
+	return o
+}
+
+// SetJanitorSweepMode sets how the janitor removes expired entries:
+// SweepLocked (default) removes them under a single lock per shard, bounded
+// by the budget and max lock hold. SweepIncremental locks the shard for a
+// batch at a time (see SetJanitorBatchSize) and continues while enough of a
+// batch had expired (see SetJanitorExpiredRatio), like Redis' active expire
+// cycle. With SweepIncremental, caches without an expiry index have their
+// entries sampled for expired ones.
+func (o *Options[K]) SetJanitorSweepMode(m SweepMode) *Options[K] {
+	o.JanitorSweepMode = m
+	return o
+}
+
+// SetJanitorBatchSize sets the number of entries an incremental sweep
+// examines per lock acquisition. Values <= 0 use 20.
+func (o *Options[K]) SetJanitorBatchSize(n int) *Options[K] {
+	o.JanitorBatchSize = n
+	return o
+}
+
+// SetJanitorExpiredRatio sets the share of expired entries in a batch above
+// which an incremental sweep takes another batch. Values <= 0 use 0.25.
+func (o *Options[K]) SetJanitorExpiredRatio(r float64) *Options[K] {
+	o.JanitorExpiredRatio = r
+	return o
+}
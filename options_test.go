@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jeltjongsma/go-cache/pkg/policies"
+	"github.com/jeltjongsma/go-cache/pkg/ttl_queue"
 )
 
 func TestOptions_Capacity(t *testing.T) {
@@ -29,6 +30,20 @@ func TestOptions_Policy(t *testing.T) {
 	}
 }
 
+func TestOptions_PolicyFactory(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.PolicyFactory != nil {
+		t.Errorf("expected nil, got factory")
+	}
+	opts.SetPolicyFactory(func() policies.Policy[int] { return policies.NewLRU[int]() })
+	if opts.PolicyFactory == nil {
+		t.Fatalf("expected factory, got nil")
+	}
+	if ptype, _ := opts.PolicyFactory().Type(); ptype != policies.TypeLRU {
+		t.Errorf("expected lru, got %s", ptype)
+	}
+}
+
 func TestOptions_NumShards(t *testing.T) {
 	opts := NewOptions[int]()
 	if opts.NumShards != 16 {
@@ -57,3 +72,95 @@ func TestOptions_DefaultTTL(t *testing.T) {
 		t.Errorf("expected 10, got %v", opts.DefaultTTL)
 	}
 }
+
+func TestOptions_MaxCost(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.MaxCost != 0 {
+		t.Errorf("expected 0, got %d", opts.MaxCost)
+	}
+	opts.SetMaxCost(1 << 20)
+	if opts.MaxCost != 1<<20 {
+		t.Errorf("expected %d, got %d", 1<<20, opts.MaxCost)
+	}
+}
+
+func TestOptions_MaxMemory(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.MaxMemory != 0 {
+		t.Errorf("expected 0, got %d", opts.MaxMemory)
+	}
+	opts.SetMaxMemory(1 << 30)
+	if opts.MaxMemory != 1<<30 {
+		t.Errorf("expected %d, got %d", 1<<30, opts.MaxMemory)
+	}
+}
+
+func TestOptions_ErrorTTL(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.ErrorTTL != 0 {
+		t.Errorf("expected 0, got %v", opts.ErrorTTL)
+	}
+	opts.SetErrorTTL(time.Second)
+	if opts.ErrorTTL != time.Second {
+		t.Errorf("expected 1s, got %v", opts.ErrorTTL)
+	}
+}
+
+func TestOptions_Refresh(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.RefreshAfter != 0 || opts.StaleTTL != 0 {
+		t.Errorf("expected 0, got %v, %v", opts.RefreshAfter, opts.StaleTTL)
+	}
+	opts.SetRefreshAfter(time.Second).SetStaleTTL(time.Minute)
+	if opts.RefreshAfter != time.Second {
+		t.Errorf("expected 1s, got %v", opts.RefreshAfter)
+	}
+	if opts.StaleTTL != time.Minute {
+		t.Errorf("expected 1m, got %v", opts.StaleTTL)
+	}
+}
+
+func TestOptions_ExpiryIndex(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.ExpiryIndex != ttl_queue.TypeHeap {
+		t.Errorf("expected heap, got %s", opts.ExpiryIndex)
+	}
+	opts.SetExpiryIndex(ttl_queue.TypeTimingWheel)
+	if opts.ExpiryIndex != ttl_queue.TypeTimingWheel {
+		t.Errorf("expected timing-wheel, got %s", opts.ExpiryIndex)
+	}
+}
+
+func TestOptions_Janitor(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.JanitorInterval != time.Second || opts.JanitorBudget != 0 || opts.JanitorMaxLockHold != time.Millisecond {
+		t.Errorf("expected 1s, 0, 1ms, got %v, %d, %v", opts.JanitorInterval, opts.JanitorBudget, opts.JanitorMaxLockHold)
+	}
+	opts.SetJanitorInterval(time.Minute).SetJanitorBudget(100).SetJanitorMaxLockHold(0)
+	if opts.JanitorInterval != time.Minute {
+		t.Errorf("expected 1m, got %v", opts.JanitorInterval)
+	}
+	if opts.JanitorBudget != 100 {
+		t.Errorf("expected 100, got %d", opts.JanitorBudget)
+	}
+	if opts.JanitorMaxLockHold != 0 {
+		t.Errorf("expected 0, got %v", opts.JanitorMaxLockHold)
+	}
+}
+
+func TestOptions_JanitorSweepMode(t *testing.T) {
+	opts := NewOptions[int]()
+	if opts.JanitorSweepMode != SweepLocked || opts.JanitorBatchSize != 20 || opts.JanitorExpiredRatio != 0.25 {
+		t.Errorf("expected locked, 20, 0.25, got %v, %d, %v", opts.JanitorSweepMode, opts.JanitorBatchSize, opts.JanitorExpiredRatio)
+	}
+	opts.SetJanitorSweepMode(SweepIncremental).SetJanitorBatchSize(50).SetJanitorExpiredRatio(0.1)
+	if opts.JanitorSweepMode != SweepIncremental {
+		t.Errorf("expected incremental, got %v", opts.JanitorSweepMode)
+	}
+	if opts.JanitorBatchSize != 50 {
+		t.Errorf("expected 50, got %d", opts.JanitorBatchSize)
+	}
+	if opts.JanitorExpiredRatio != 0.1 {
+		t.Errorf("expected 0.1, got %v", opts.JanitorExpiredRatio)
+	}
+}
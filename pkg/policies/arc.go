@@ -0,0 +1,192 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+// ARC is an Adaptive Replacement Cache policy (Megiddo & Modha).
+// Resident keys live in t1 (seen once recently) or t2 (seen at least twice).
+// Evicted keys are remembered in the ghost lists b1 and b2. A miss on a ghost
+// shifts the target size p of t1: ghosts in b1 mean recency is paying off,
+// ghosts in b2 mean frequency is. ARC learns about those misses through
+// OnMiss, so it should be used by a shard that reports misses.
+type ARC[K comparable] struct {
+	cap            int
+	p              int // target size of t1
+	t1, t2, b1, b2 *keyList[K]
+	nodes          map[K]*keyNode[K]
+	// last miss hit b2, which tips a tie in Evict towards t1
+	ghostHitB2 bool
+}
+
+// NewARC returns an ARC policy for a shard that holds at most cap keys.
+// cap is used to bound the ghost lists. With cap == 0 (a shard bounded by
+// cost or memory) they are bounded by the number of resident keys instead.
+func NewARC[K comparable](cap int) *ARC[K] {
+	return &ARC[K]{
+		cap:   max(cap, 0),
+		t1:    &keyList[K]{},
+		t2:    &keyList[K]{},
+		b1:    &keyList[K]{},
+		b2:    &keyList[K]{},
+		nodes: make(map[K]*keyNode[K]),
+	}
+}
+
+func (p *ARC[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeARC, t
+}
+
+// fails silently when key is not resident
+func (p *ARC[K]) OnHit(k K) {
+	n := p.nodes[k]
+	if n == nil || !p.resident(n) {
+		return
+	}
+	if n.list == p.t2 {
+		p.t2.moveToFront(n)
+		return
+	}
+	p.t1.remove(n)
+	p.t2.pushFront(n)
+}
+
+func (p *ARC[K]) OnSet(k K) {
+	p.ghostHitB2 = false
+
+	if n := p.nodes[k]; n != nil {
+		if p.resident(n) {
+			p.OnHit(k)
+			return
+		}
+		// seen before: promote ghost straight to t2
+		n.list.remove(n)
+		p.t2.pushFront(n)
+		return
+	}
+
+	n := &keyNode[K]{key: k}
+	p.nodes[k] = n
+	p.t1.pushFront(n)
+	p.trimGhosts()
+}
+
+func (p *ARC[K]) OnDel(k K) {
+	if n := p.nodes[k]; n != nil {
+		n.list.remove(n)
+		delete(p.nodes, k)
+	}
+}
+
+// OnMiss adapts the target size of t1 when k is a ghost.
+func (p *ARC[K]) OnMiss(k K) {
+	n := p.nodes[k]
+	if n == nil {
+		return
+	}
+	switch n.list {
+	case p.b1:
+		delta := max(1, p.b2.len/p.b1.len)
+		p.p = min(p.p+delta, p.target())
+	case p.b2:
+		delta := max(1, p.b1.len/p.b2.len)
+		p.p = max(p.p-delta, 0)
+		p.ghostHitB2 = true
+	}
+}
+
+func (p *ARC[K]) Evict() (K, bool) {
+	var n *keyNode[K]
+	t1 := p.t1.len
+	switch {
+	case t1 == 0 && p.t2.len == 0:
+		var zero K
+		return zero, false
+	case t1 > 0 && (t1 > p.p || (p.ghostHitB2 && t1 == p.p) || p.t2.len == 0):
+		n = p.t1.back()
+		p.t1.remove(n)
+		p.b1.pushFront(n)
+	default:
+		n = p.t2.back()
+		p.t2.remove(n)
+		p.b2.pushFront(n)
+	}
+	return n.key, true
+}
+
+func (p *ARC[K]) Reset() {
+	p.t1.reset()
+	p.t2.reset()
+	p.b1.reset()
+	p.b2.reset()
+	clear(p.nodes)
+	p.p = 0
+	p.ghostHitB2 = false
+}
+
+func (p *ARC[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+// Len returns the number of resident keys (ghosts are not counted).
+func (p *ARC[K]) Len() int {
+	return p.t1.len + p.t2.len
+}
+
+// KeyOverhead estimates the bytes spent per key: its node and map entry,
+// doubled because the ghost lists track up to as many keys as are resident.
+func (p *ARC[K]) KeyOverhead() int64 {
+	return 2 * (sizer.Static[keyNode[K]]() + sizer.MapEntry[K, *keyNode[K]]())
+}
+
+func (p *ARC[K]) resident(n *keyNode[K]) bool {
+	return n.list == p.t1 || n.list == p.t2
+}
+
+// target is the upper bound of p, without a capacity it is the number of
+// resident keys.
+func (p *ARC[K]) target() int {
+	if p.cap > 0 {
+		return p.cap
+	}
+	return p.Len()
+}
+
+// trimGhosts keeps |t1|+|b1| <= c and the total size <= 2*c, where c is
+// the capacity or, without one, the number of resident keys.
+func (p *ARC[K]) trimGhosts() {
+	c := p.target()
+	for p.b1.len > 0 && p.t1.len+p.b1.len > c {
+		p.dropGhost(p.b1)
+	}
+	for p.b2.len > 0 && p.t1.len+p.t2.len+p.b1.len+p.b2.len > 2*c {
+		p.dropGhost(p.b2)
+	}
+}
+
+func (p *ARC[K]) dropGhost(l *keyList[K]) {
+	n := l.back()
+	l.remove(n)
+	delete(p.nodes, n.key)
+}
+
+func (p *ARC[K]) validate() error {
+	for _, l := range []*keyList[K]{p.t1, p.t2, p.b1, p.b2} {
+		if err := l.validate(p.nodes); err != nil {
+			return err
+		}
+	}
+	if total := p.t1.len + p.t2.len + p.b1.len + p.b2.len; total != len(p.nodes) {
+		return fmt.Errorf("len(map): %d != len(lists): %d", len(p.nodes), total)
+	}
+	if p.p < 0 || (p.cap > 0 && p.p > p.cap) {
+		return fmt.Errorf("target p=%d out of range", p.p)
+	}
+	return nil
+}
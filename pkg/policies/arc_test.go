@@ -0,0 +1,265 @@
+package policies
+
+import "testing"
+
+func TestNewARC(t *testing.T) {
+	p := NewARC[int](4)
+	if p.nodes == nil {
+		t.Fatalf("expected nodes to be set, got nil")
+	}
+	if p.cap != 4 {
+		t.Errorf("expected cap=4, got %d", p.cap)
+	}
+	if p.p != 0 {
+		t.Errorf("expected p=0, got %d", p.p)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+}
+
+func TestARC_Type(t *testing.T) {
+	p := NewARC[int](4)
+	ptype, ktype := p.Type()
+	if ptype != TypeARC {
+		t.Errorf("expected 'ARC', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestARC_OnSet(t *testing.T) {
+	p := NewARC[int](4)
+	p.OnSet(1)
+	p.OnSet(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := p.t1.len; l != 2 {
+		t.Errorf("expected len(t1)=2, got %d", l)
+	}
+
+	// second set promotes to t2
+	p.OnSet(1)
+	if n := p.nodes[1]; n.list != p.t2 {
+		t.Errorf("expected key=1 in t2")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestARC_OnHit(t *testing.T) {
+	p := NewARC[int](4)
+	p.OnSet(1)
+	p.OnSet(2)
+
+	p.OnHit(1)
+	if n := p.nodes[1]; n.list != p.t2 {
+		t.Errorf("expected key=1 in t2")
+	}
+	p.OnHit(2)
+	p.OnHit(1)
+	if p.t2.head.key != 1 {
+		t.Errorf("expected key=1 in front of t2, got %d", p.t2.head.key)
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(3)
+	if p.Len() != 2 {
+		t.Errorf("expected len=2, got %d", p.Len())
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestARC_OnHit_Ghost(t *testing.T) {
+	p := NewARC[int](2)
+	p.OnSet(1)
+	p.Evict() // 1 -> b1
+
+	p.OnHit(1)
+	if n := p.nodes[1]; n.list != p.b1 {
+		t.Errorf("expected key=1 to stay in b1")
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+}
+
+func TestARC_OnDel(t *testing.T) {
+	p := NewARC[int](4)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(2)
+
+	p.OnDel(1)
+	p.OnDel(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if len(p.nodes) != 0 {
+		t.Errorf("expected no nodes, got %d", len(p.nodes))
+	}
+}
+
+func TestARC_Evict(t *testing.T) {
+	p := NewARC[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	p.OnHit(1)
+
+	// p=0, so t1 is over target: evict LRU of t1
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 2 {
+		t.Errorf("expected k=2, got %d", k)
+	}
+	if n := p.nodes[2]; n == nil || n.list != p.b1 {
+		t.Fatalf("expected key=2 in b1")
+	}
+
+	k, _ = p.Evict()
+	if k != 3 {
+		t.Errorf("expected k=3, got %d", k)
+	}
+	// t1 empty, fall back to t2
+	k, _ = p.Evict()
+	if k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+	if n := p.nodes[1]; n == nil || n.list != p.b2 {
+		t.Fatalf("expected key=1 in b2")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestARC_Evict_Empty(t *testing.T) {
+	p := NewARC[int](3)
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestARC_OnMiss(t *testing.T) {
+	p := NewARC[int](2)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(2)
+	p.Evict() // 1 -> b1
+	p.Evict() // 2 -> b2
+
+	// ghost hit in b1 grows t1 target
+	p.OnMiss(1)
+	if p.p != 1 {
+		t.Errorf("expected p=1, got %d", p.p)
+	}
+	// ghost hit in b2 shrinks it
+	p.OnMiss(2)
+	if p.p != 0 {
+		t.Errorf("expected p=0, got %d", p.p)
+	}
+	if !p.ghostHitB2 {
+		t.Errorf("expected ghostHitB2=true, got false")
+	}
+
+	// re-inserting a ghost goes straight to t2
+	p.OnSet(1)
+	if n := p.nodes[1]; n.list != p.t2 {
+		t.Errorf("expected key=1 in t2")
+	}
+	if p.ghostHitB2 {
+		t.Errorf("expected ghostHitB2=false, got true")
+	}
+
+	// unknown keys are ignored
+	p.OnMiss(3)
+	if p.p != 0 {
+		t.Errorf("expected p=0, got %d", p.p)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestARC_trimGhosts(t *testing.T) {
+	p := NewARC[int](2)
+	for i := range 10 {
+		if p.Len() == 2 {
+			p.Evict()
+		}
+		p.OnSet(i)
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := p.t1.len + p.b1.len; l > 2 {
+		t.Errorf("expected |t1|+|b1| <= 2, got %d", l)
+	}
+	if l := len(p.nodes); l > 4 {
+		t.Errorf("expected at most 4 nodes, got %d", l)
+	}
+}
+
+// Without a capacity ghosts are bounded by the resident keys.
+func TestARC_trimGhosts_NoCapacity(t *testing.T) {
+	p := NewARC[int](0)
+	for i := range 10_000 {
+		if p.Len() == 100 {
+			p.Evict()
+		}
+		p.OnSet(i)
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := len(p.nodes); l > 200 {
+		t.Errorf("expected at most 200 nodes, got %d", l)
+	}
+}
+
+func TestARC_Reset(t *testing.T) {
+	p := NewARC[int](2)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+	p.OnMiss(1)
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if len(p.nodes) != 0 {
+		t.Errorf("expected no nodes, got %d", len(p.nodes))
+	}
+	if p.p != 0 {
+		t.Errorf("expected p=0, got %d", p.p)
+	}
+}
+
+func TestARC_Len(t *testing.T) {
+	p := NewARC[int](2)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+
+	if p.Len() != 1 {
+		t.Errorf("expected 1, got %d", p.Len())
+	}
+}
@@ -0,0 +1,148 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+const (
+	slotUsed uint8 = 1 << iota
+	slotRef
+)
+
+// CLOCK approximates LRU with a reference bit per key and a hand sweeping
+// over a ring of slots. A hit only sets the bit. On eviction the hand clears
+// set bits until it finds a key without one.
+// Keys are stored by value in flat slices and indexed by slot number, so
+// the policy doesn't allocate a node per key.
+type CLOCK[K comparable] struct {
+	keys  []K
+	flags []uint8
+	index map[K]int32
+	free  []int32
+	hand  int
+}
+
+// NewCLOCK returns a CLOCK policy with room for cap keys, the ring grows
+// when more keys are set.
+func NewCLOCK[K comparable](cap int) *CLOCK[K] {
+	cap = max(cap, 0)
+	return &CLOCK[K]{
+		keys:  make([]K, 0, cap),
+		flags: make([]uint8, 0, cap),
+		index: make(map[K]int32, cap),
+	}
+}
+
+func (p *CLOCK[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeCLOCK, t
+}
+
+// fails silently when key is not in policy
+func (p *CLOCK[K]) OnHit(k K) {
+	if i, ok := p.index[k]; ok {
+		p.flags[i] |= slotRef
+	}
+}
+
+func (p *CLOCK[K]) OnSet(k K) {
+	if _, ok := p.index[k]; ok {
+		p.OnHit(k)
+		return
+	}
+	var i int32
+	if n := len(p.free); n > 0 {
+		i = p.free[n-1]
+		p.free = p.free[:n-1]
+		p.keys[i] = k
+		p.flags[i] = slotUsed
+	} else {
+		i = int32(len(p.keys))
+		p.keys = append(p.keys, k)
+		p.flags = append(p.flags, slotUsed)
+	}
+	p.index[k] = i
+}
+
+func (p *CLOCK[K]) OnDel(k K) {
+	if i, ok := p.index[k]; ok {
+		p.release(i)
+	}
+}
+
+func (p *CLOCK[K]) Evict() (K, bool) {
+	if len(p.index) == 0 {
+		var zero K
+		return zero, false
+	}
+	for {
+		i := p.hand
+		p.hand = (p.hand + 1) % len(p.keys)
+		switch {
+		case p.flags[i]&slotUsed == 0:
+		case p.flags[i]&slotRef != 0:
+			p.flags[i] &^= slotRef
+		default:
+			k := p.keys[i]
+			p.release(int32(i))
+			return k, true
+		}
+	}
+}
+
+func (p *CLOCK[K]) Reset() {
+	clear(p.keys)
+	p.keys = p.keys[:0]
+	p.flags = p.flags[:0]
+	p.free = p.free[:0]
+	clear(p.index)
+	p.hand = 0
+}
+
+func (p *CLOCK[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+func (p *CLOCK[K]) Len() int {
+	return len(p.index)
+}
+
+// KeyOverhead estimates the bytes spent per key: its slot, flags and index
+// entry.
+func (p *CLOCK[K]) KeyOverhead() int64 {
+	return sizer.Static[K]() + 1 + sizer.MapEntry[K, int32]()
+}
+
+// release empties slot i and makes it available for reuse.
+func (p *CLOCK[K]) release(i int32) {
+	delete(p.index, p.keys[i])
+	var zero K
+	p.keys[i] = zero
+	p.flags[i] = 0
+	p.free = append(p.free, i)
+}
+
+func (p *CLOCK[K]) validate() error {
+	used := 0
+	for i, f := range p.flags {
+		if f&slotUsed == 0 {
+			continue
+		}
+		used++
+		if j, ok := p.index[p.keys[i]]; !ok || int(j) != i {
+			return fmt.Errorf("slot %d ('%v') not in index", i, p.keys[i])
+		}
+	}
+	if used != len(p.index) {
+		return fmt.Errorf("len(index): %d != used slots: %d", len(p.index), used)
+	}
+	if used+len(p.free) != len(p.keys) {
+		return fmt.Errorf("used: %d + free: %d != slots: %d", used, len(p.free), len(p.keys))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package policies
+
+import "testing"
+
+func TestNewCLOCK(t *testing.T) {
+	p := NewCLOCK[int](10)
+	if p.index == nil {
+		t.Fatalf("expected index to be set, got nil")
+	}
+	if c := cap(p.keys); c != 10 {
+		t.Errorf("expected cap(keys)=10, got %d", c)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+}
+
+func TestCLOCK_Type(t *testing.T) {
+	p := NewCLOCK[int](10)
+	ptype, ktype := p.Type()
+	if ptype != TypeCLOCK {
+		t.Errorf("expected 'CLOCK', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestCLOCK_OnSet(t *testing.T) {
+	p := NewCLOCK[int](2)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3) // ring grows
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := len(p.keys); l != 3 {
+		t.Errorf("expected 3 slots, got %d", l)
+	}
+
+	p.OnSet(1) // duplicate sets reference bit
+	if p.flags[p.index[1]]&slotRef == 0 {
+		t.Errorf("expected key=1 referenced")
+	}
+	if p.Len() != 3 {
+		t.Errorf("expected len=3, got %d", p.Len())
+	}
+}
+
+func TestCLOCK_OnHit(t *testing.T) {
+	p := NewCLOCK[int](2)
+	p.OnSet(1)
+
+	p.OnHit(1)
+	if p.flags[p.index[1]]&slotRef == 0 {
+		t.Errorf("expected key=1 referenced")
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(2)
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+}
+
+func TestCLOCK_OnDel(t *testing.T) {
+	p := NewCLOCK[int](2)
+	p.OnSet(1)
+	p.OnSet(2)
+
+	p.OnDel(1)
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 1 {
+		t.Fatalf("expected len=1, got %d", p.Len())
+	}
+
+	// slot is reused
+	p.OnSet(3)
+	if l := len(p.keys); l != 2 {
+		t.Errorf("expected 2 slots, got %d", l)
+	}
+	if i := p.index[3]; i != 0 {
+		t.Errorf("expected key=3 in slot 0, got %d", i)
+	}
+}
+
+func TestCLOCK_Evict(t *testing.T) {
+	p := NewCLOCK[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	p.OnHit(1)
+	p.OnHit(2)
+
+	// 1 and 2 get a second chance
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 3 {
+		t.Errorf("expected k=3, got %d", k)
+	}
+	if p.flags[p.index[1]]&slotRef != 0 {
+		t.Errorf("expected key=1 reference bit cleared")
+	}
+
+	// hand wraps around
+	k, _ = p.Evict()
+	if k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestCLOCK_Evict_Empty(t *testing.T) {
+	p := NewCLOCK[int](3)
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestCLOCK_Reset(t *testing.T) {
+	p := NewCLOCK[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if p.hand != 0 {
+		t.Errorf("expected hand=0, got %d", p.hand)
+	}
+}
+
+func TestCLOCK_Len(t *testing.T) {
+	p := NewCLOCK[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if p.Len() != 3 {
+		t.Errorf("expected 3, got %d", p.Len())
+	}
+}
@@ -0,0 +1,364 @@
+package policies
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+const (
+	pageHot uint8 = iota + 1
+	pageCold
+	pageTest // non-resident cold page in its test period
+
+	pageKind uint8 = 0x3
+	pageRef  uint8 = 1 << 7
+)
+
+// CLOCKPro is the CLOCK-Pro policy (Jiang, Chen & Zhang). Keys are hot or
+// cold; a cold key that is evicted stays on the clock as a non-resident test
+// page for a while. Setting a key during its test period proves it has a
+// short reuse distance, so it comes back hot and the target size of the cold
+// area grows; test periods that run out shrink it again. Three hands sweep
+// the clock: the cold hand finds victims, the hot hand demotes hot keys that
+// weren't referenced and the test hand ends test periods.
+// Like CLOCK, pages live in flat slices (a circular list linked by slot
+// number) instead of a node per key.
+// Hands move one page per step in bounded loops, the hot and test hands never
+// call back into the cold hand.
+type CLOCKPro[K comparable] struct {
+	keys       []K
+	next, prev []int32
+	flags      []uint8
+	index      map[K]int32
+	free       []int32
+
+	handHot, handCold, handTest    int32
+	memMax                         int // 0 without a capacity, see limit
+	memCold                        int // target size of the cold area
+	countHot, countCold, countTest int
+	// keys that became non-resident but weren't returned by Evict yet
+	pending []K
+}
+
+// NewCLOCKPro returns a CLOCK-Pro policy for a shard that holds at most cap
+// keys. cap <= 0 means the shard is bounded by cost or memory only: the clock
+// is then sized by the number of resident keys.
+func NewCLOCKPro[K comparable](cap int) *CLOCKPro[K] {
+	cap = max(cap, 0)
+	memCold := cap
+	if cap == 0 {
+		memCold = math.MaxInt // clamped to the resident keys on eviction
+	}
+	return &CLOCKPro[K]{
+		keys:     make([]K, 0, cap),
+		next:     make([]int32, 0, cap),
+		prev:     make([]int32, 0, cap),
+		flags:    make([]uint8, 0, cap),
+		index:    make(map[K]int32, cap),
+		handHot:  -1,
+		handCold: -1,
+		handTest: -1,
+		memMax:   cap,
+		memCold:  memCold,
+	}
+}
+
+func (p *CLOCKPro[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeCLOCKPro, t
+}
+
+// fails silently when key is not resident
+func (p *CLOCKPro[K]) OnHit(k K) {
+	if i, ok := p.index[k]; ok && p.kind(i) != pageTest {
+		p.flags[i] |= pageRef
+	}
+}
+
+func (p *CLOCKPro[K]) OnSet(k K) {
+	i, ok := p.index[k]
+	if !ok {
+		p.add(k, pageCold)
+		p.countCold++
+		return
+	}
+	if p.kind(i) != pageTest {
+		p.flags[i] |= pageRef
+		return
+	}
+	// set during its test period: cold area is too small
+	if p.memCold < p.limit() {
+		p.memCold++
+	}
+	p.del(i)
+	p.countTest--
+	p.add(k, pageHot)
+	p.countHot++
+}
+
+func (p *CLOCKPro[K]) OnDel(k K) {
+	if j := slices.Index(p.pending, k); j >= 0 {
+		p.pending = slices.Delete(p.pending, j, j+1)
+	}
+	i, ok := p.index[k]
+	if !ok {
+		return
+	}
+	switch p.kind(i) {
+	case pageHot:
+		p.countHot--
+	case pageCold:
+		p.countCold--
+	case pageTest:
+		p.countTest--
+	}
+	p.del(i)
+}
+
+func (p *CLOCKPro[K]) Evict() (K, bool) {
+	if len(p.pending) == 0 {
+		if p.countHot+p.countCold == 0 {
+			var zero K
+			return zero, false
+		}
+		p.memCold = min(p.memCold, p.limit())
+		for len(p.pending) == 0 {
+			if p.countCold == 0 {
+				// only hot keys, demote one for the cold hand
+				p.runHandHot()
+				continue
+			}
+			p.runHandCold()
+		}
+	}
+	k := p.pending[0]
+	p.pending = slices.Delete(p.pending, 0, 1)
+	return k, true
+}
+
+func (p *CLOCKPro[K]) Reset() {
+	clear(p.keys)
+	p.keys = p.keys[:0]
+	p.next = p.next[:0]
+	p.prev = p.prev[:0]
+	p.flags = p.flags[:0]
+	p.free = p.free[:0]
+	clear(p.index)
+	p.pending = p.pending[:0]
+	p.handHot, p.handCold, p.handTest = -1, -1, -1
+	p.memCold = p.memMax
+	if p.memMax == 0 {
+		p.memCold = math.MaxInt
+	}
+	p.countHot, p.countCold, p.countTest = 0, 0, 0
+}
+
+func (p *CLOCKPro[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+// Len returns the number of resident keys (test pages are not counted).
+func (p *CLOCKPro[K]) Len() int {
+	return p.countHot + p.countCold + len(p.pending)
+}
+
+// KeyOverhead estimates the bytes spent per key: its page, links, flags and
+// index entry, doubled because test pages can be as many as resident keys.
+func (p *CLOCKPro[K]) KeyOverhead() int64 {
+	return 2 * (sizer.Static[K]() + 4 + 4 + 1 + sizer.MapEntry[K, int32]())
+}
+
+func (p *CLOCKPro[K]) kind(i int32) uint8 {
+	return p.flags[i] & pageKind
+}
+
+// limit is the number of resident keys the clock is sized for: the
+// capacity or, without one, the keys resident now.
+func (p *CLOCKPro[K]) limit() int {
+	if p.memMax > 0 {
+		return p.memMax
+	}
+	return max(p.Len(), 1)
+}
+
+// hotTarget is the number of hot keys the clock keeps. With room for a single
+// key there is no room for hot keys, the key stays cold.
+func (p *CLOCKPro[K]) hotTarget() int {
+	return max(p.limit()-p.memCold, 0)
+}
+
+// runHandCold turns the cold page under the hand hot when it was referenced,
+// otherwise it is evicted and starts its test period. Then the test and hot
+// hands run until the test pages and hot keys are within bounds.
+func (p *CLOCKPro[K]) runHandCold() {
+	i := p.handCold
+	if p.kind(i) == pageCold {
+		if p.flags[i]&pageRef != 0 {
+			p.flags[i] = pageHot
+			p.countCold--
+			p.countHot++
+		} else {
+			p.flags[i] = pageTest
+			p.countCold--
+			p.countTest++
+			p.pending = append(p.pending, p.keys[i])
+		}
+	}
+	p.handCold = p.next[p.handCold]
+	// each step ends a test period or moves on, so a full turn of the
+	// clock ends at least one
+	for p.countTest > p.limit() {
+		p.runHandTest()
+	}
+	// each step demotes a hot page or clears its reference, so two turns
+	// of the clock demote at least one
+	for p.countHot > 0 && p.countHot > p.hotTarget() {
+		p.runHandHot()
+	}
+}
+
+// runHandHot demotes the hot page under the hand unless it was referenced.
+// Test pages it passes end their test period, as in the paper.
+func (p *CLOCKPro[K]) runHandHot() {
+	i := p.handHot
+	switch p.kind(i) {
+	case pageHot:
+		if p.flags[i]&pageRef != 0 {
+			p.flags[i] &^= pageRef
+		} else {
+			p.flags[i] = pageCold
+			p.countHot--
+			p.countCold++
+		}
+	case pageTest:
+		p.endTest(i) // moves the hand back
+		if p.handHot < 0 {
+			return
+		}
+	}
+	p.handHot = p.next[p.handHot]
+}
+
+// runHandTest ends the test period of the page under the hand.
+func (p *CLOCKPro[K]) runHandTest() {
+	i := p.handTest
+	if p.kind(i) == pageTest {
+		p.endTest(i) // moves the hand back
+		if p.handTest < 0 {
+			return
+		}
+	}
+	p.handTest = p.next[p.handTest]
+}
+
+// endTest removes test page i: the key wasn't set again in time, so the cold
+// area shrinks.
+func (p *CLOCKPro[K]) endTest(i int32) {
+	p.del(i)
+	p.countTest--
+	if p.memCold > 1 {
+		p.memCold--
+	}
+}
+
+// add links a new page in front of the hot hand, the spot the hands will
+// reach last.
+func (p *CLOCKPro[K]) add(k K, kind uint8) {
+	var i int32
+	if n := len(p.free); n > 0 {
+		i = p.free[n-1]
+		p.free = p.free[:n-1]
+		p.keys[i] = k
+		p.flags[i] = kind
+	} else {
+		i = int32(len(p.keys))
+		p.keys = append(p.keys, k)
+		p.flags = append(p.flags, kind)
+		p.next = append(p.next, 0)
+		p.prev = append(p.prev, 0)
+	}
+	p.index[k] = i
+
+	if p.handHot < 0 {
+		p.next[i], p.prev[i] = i, i
+		p.handHot, p.handCold, p.handTest = i, i, i
+		return
+	}
+	h := p.handHot
+	before := p.prev[h]
+	p.next[before], p.prev[i] = i, before
+	p.next[i], p.prev[h] = h, i
+	if p.handCold == p.handHot {
+		p.handCold = i
+	}
+}
+
+// del unlinks page i, hands pointing at it move back one page.
+func (p *CLOCKPro[K]) del(i int32) {
+	delete(p.index, p.keys[i])
+	if p.next[i] == i {
+		p.handHot, p.handCold, p.handTest = -1, -1, -1
+	} else {
+		before, after := p.prev[i], p.next[i]
+		if p.handHot == i {
+			p.handHot = before
+		}
+		if p.handCold == i {
+			p.handCold = before
+		}
+		if p.handTest == i {
+			p.handTest = before
+		}
+		p.next[before], p.prev[after] = after, before
+	}
+	var zero K
+	p.keys[i] = zero
+	p.flags[i] = 0
+	p.free = append(p.free, i)
+}
+
+func (p *CLOCKPro[K]) validate() error {
+	var hot, cold, test int
+	if start := p.handHot; start >= 0 {
+		i := start
+		for {
+			if p.prev[p.next[i]] != i {
+				return fmt.Errorf("page %d has broken links", i)
+			}
+			if j, ok := p.index[p.keys[i]]; !ok || j != i {
+				return fmt.Errorf("page %d ('%v') not in index", i, p.keys[i])
+			}
+			switch p.kind(i) {
+			case pageHot:
+				hot++
+			case pageCold:
+				cold++
+			case pageTest:
+				test++
+			default:
+				return fmt.Errorf("page %d in ring is free", i)
+			}
+			i = p.next[i]
+			if i == start {
+				break
+			}
+		}
+	}
+	if hot != p.countHot || cold != p.countCold || test != p.countTest {
+		return fmt.Errorf("counts (%d, %d, %d) != ring (%d, %d, %d)",
+			p.countHot, p.countCold, p.countTest, hot, cold, test)
+	}
+	if total := hot + cold + test; total != len(p.index) {
+		return fmt.Errorf("len(index): %d != len(ring): %d", len(p.index), total)
+	}
+	if p.memCold < 1 || (p.memMax > 0 && p.memCold > p.memMax) {
+		return fmt.Errorf("memCold=%d out of range", p.memCold)
+	}
+	return nil
+}
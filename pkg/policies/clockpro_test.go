@@ -0,0 +1,269 @@
+package policies
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNewCLOCKPro(t *testing.T) {
+	p := NewCLOCKPro[int](10)
+	if p.index == nil {
+		t.Fatalf("expected index to be set, got nil")
+	}
+	if p.memMax != 10 || p.memCold != 10 {
+		t.Errorf("expected memMax=memCold=10, got %d, %d", p.memMax, p.memCold)
+	}
+	if p.handHot != -1 || p.handCold != -1 || p.handTest != -1 {
+		t.Errorf("expected hands=-1, got %d, %d, %d", p.handHot, p.handCold, p.handTest)
+	}
+}
+
+func TestCLOCKPro_Type(t *testing.T) {
+	p := NewCLOCKPro[int](10)
+	ptype, ktype := p.Type()
+	if ptype != TypeCLOCKPro {
+		t.Errorf("expected 'CLOCK-Pro', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestCLOCKPro_OnSet(t *testing.T) {
+	p := NewCLOCKPro[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.countCold != 2 {
+		t.Errorf("expected 2 cold pages, got %d", p.countCold)
+	}
+
+	p.OnSet(1) // duplicate sets reference bit
+	if p.flags[p.index[1]]&pageRef == 0 {
+		t.Errorf("expected key=1 referenced")
+	}
+}
+
+func TestCLOCKPro_OnHit(t *testing.T) {
+	p := NewCLOCKPro[int](10)
+	p.OnSet(1)
+
+	p.OnHit(1)
+	if p.flags[p.index[1]]&pageRef == 0 {
+		t.Errorf("expected key=1 referenced")
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(2)
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+}
+
+func TestCLOCKPro_Evict(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	// evicted key stays as a test page
+	if i, ok := p.index[k]; !ok || p.kind(i) != pageTest {
+		t.Errorf("expected key=%d to be a test page", k)
+	}
+	if p.Len() != 2 {
+		t.Errorf("expected len=2, got %d", p.Len())
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestCLOCKPro_Evict_Empty(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestCLOCKPro_OnSet_TestPage(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	p.OnSet(1)
+	p.Evict() // 1 is a test page
+	p.memCold = 2
+
+	p.OnSet(1)
+
+	i, ok := p.index[1]
+	if !ok || p.kind(i) != pageHot {
+		t.Fatalf("expected key=1 to be hot")
+	}
+	if p.memCold != 3 {
+		t.Errorf("expected memCold=3, got %d", p.memCold)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestCLOCKPro_runHandTest(t *testing.T) {
+	p := NewCLOCKPro[int](2)
+	for i := range 10 {
+		if p.Len() == 2 {
+			p.Evict()
+		}
+		p.OnSet(i)
+	}
+
+	// test pages are bounded by memMax and expire, shrinking the cold area
+	if p.countTest > p.memMax {
+		t.Errorf("expected at most %d test pages, got %d", p.memMax, p.countTest)
+	}
+	if p.memCold != 1 {
+		t.Errorf("expected memCold=1, got %d", p.memCold)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestCLOCKPro_OnDel(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	k, _ := p.Evict() // k is a test page
+
+	p.OnDel(k)
+	p.OnDel(3)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+	if len(p.index) != 1 {
+		t.Errorf("expected 1 page, got %d", len(p.index))
+	}
+}
+
+// simulates a shard and checks the policy stays in sync with it
+func TestCLOCKPro_Workload(t *testing.T) {
+	clockProWorkload(t, NewCLOCKPro[int](50), 50)
+}
+
+// A single key can be hit and evicted, the hands don't chase each other.
+func TestCLOCKPro_Cap1(t *testing.T) {
+	p := NewCLOCKPro[int](1)
+	p.OnSet(1)
+	p.OnHit(1)
+	if k, ok := p.Evict(); !ok || k != 1 {
+		t.Fatalf("expected (1, true), got (%d, %v)", k, ok)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	clockProWorkload(t, NewCLOCKPro[int](1), 1)
+}
+
+// Without a capacity the clock is sized by the resident keys, so keys still
+// become hot.
+func TestCLOCKPro_NoCapacity(t *testing.T) {
+	p := NewCLOCKPro[int](0)
+	clockProWorkload(t, p, 50)
+	if p.countHot == 0 {
+		t.Errorf("expected hot keys")
+	}
+	if p.countTest > p.Len() {
+		t.Errorf("expected at most %d test pages, got %d", p.Len(), p.countTest)
+	}
+}
+
+// clockProWorkload runs a random workload against p, evicting whenever the
+// store holds size keys.
+func clockProWorkload(t *testing.T, p *CLOCKPro[int], size int) {
+	t.Helper()
+	store := make(map[int]struct{})
+	r := rand.New(rand.NewSource(1))
+
+	for i := range 100_000 {
+		k := r.Intn(200)
+		if r.Intn(2) == 0 {
+			k = r.Intn(20) // hot set
+		}
+		switch r.Intn(10) {
+		case 0:
+			if _, ok := store[k]; ok {
+				delete(store, k)
+				p.OnDel(k)
+			}
+		case 1, 2, 3:
+			if _, ok := store[k]; ok {
+				p.OnHit(k)
+				continue
+			}
+			for len(store) >= size {
+				victim, ok := p.Evict()
+				if !ok {
+					t.Fatalf("expected victim, got none")
+				}
+				if _, ok := store[victim]; !ok {
+					t.Fatalf("victim %d not in store", victim)
+				}
+				delete(store, victim)
+			}
+			store[k] = struct{}{}
+			p.OnSet(k)
+		default:
+			if _, ok := store[k]; ok {
+				p.OnHit(k)
+			}
+		}
+		if i%1000 == 0 {
+			if err := p.validate(); err != nil {
+				t.Fatalf("policy not valid: %v", err)
+			}
+			if p.Len() != len(store) {
+				t.Fatalf("expected len=%d, got %d", len(store), p.Len())
+			}
+		}
+	}
+}
+
+func TestCLOCKPro_Reset(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if len(p.index) != 0 {
+		t.Errorf("expected no pages, got %d", len(p.index))
+	}
+}
+
+func TestCLOCKPro_Len(t *testing.T) {
+	p := NewCLOCKPro[int](3)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if p.Len() != 3 {
+		t.Errorf("expected 3, got %d", p.Len())
+	}
+}
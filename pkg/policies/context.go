@@ -13,9 +13,37 @@ type Policy[K comparable] interface {
 	Len() int
 }
 
+// MissObserver is implemented by policies that need to learn about misses,
+// e.g. to detect that a key they evicted earlier is requested again.
+type MissObserver[K comparable] interface {
+	OnMiss(K)
+}
+
+// Admitter is implemented by policies that may refuse a new key.
+// Admit is consulted when the shard is full, before anything is evicted;
+// returning false leaves the shard untouched and the write fails.
+type Admitter[K comparable] interface {
+	Admit(K) bool
+}
+
+// MemoryReporter is implemented by policies that can estimate the bytes they
+// spend per key, so the shard can count them against a memory budget.
+type MemoryReporter interface {
+	KeyOverhead() int64
+}
+
 type PolicyType string
 
 const (
-	TypeFIFO PolicyType = "FIFO"
-	TypeLRU  PolicyType = "LRU"
+	TypeFIFO       PolicyType = "FIFO"
+	TypeLRU        PolicyType = "LRU"
+	TypeLFU        PolicyType = "LFU"
+	TypeARC        PolicyType = "ARC"
+	TypeWTinyLFU   PolicyType = "W-TinyLFU"
+	TypeSIEVE      PolicyType = "SIEVE"
+	TypeS3FIFO     PolicyType = "S3-FIFO"
+	TypeCLOCK      PolicyType = "CLOCK"
+	TypeCLOCKPro   PolicyType = "CLOCK-Pro"
+	TypeSampledLRU PolicyType = "Sampled-LRU"
+	TypeSampledLFU PolicyType = "Sampled-LFU"
 )
@@ -1,14 +1,33 @@
 package policies
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
 
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+// minRingSize is the smallest ring a FIFO will shrink to.
+const minRingSize = 16
+
+// FIFO evicts keys in insertion order.
+// Keys live in a ring buffer, indexed by their absolute position in the
+// queue. Deleting a key leaves a hole that Evict skips, so every operation
+// is O(1) (amortized). The ring is compacted once it holds more holes than
+// keys and shrinks when it is mostly empty, so memory is given back.
 type FIFO[K comparable] struct {
-	keys []K
+	ring       []K
+	live       []bool
+	index      map[K]int
+	head, tail int // absolute positions, the queue is [head, tail)
+	holes      int
 }
 
 func NewFIFO[K comparable]() *FIFO[K] {
 	return &FIFO[K]{
-		keys: make([]K, 0),
+		ring:  make([]K, minRingSize),
+		live:  make([]bool, minRingSize),
+		index: make(map[K]int),
 	}
 }
 
@@ -21,38 +40,143 @@ func (p *FIFO[K]) OnHit(key K) {
 	// No action needed on hit for FIFO
 }
 
+// OnSet appends key to the queue, keys already in the queue keep their
+// position.
 func (p *FIFO[K]) OnSet(key K) {
-	p.keys = append(p.keys, key)
+	if _, ok := p.index[key]; ok {
+		return
+	}
+	if p.tail-p.head == len(p.ring) {
+		p.compact()
+	}
+	i := p.slot(p.tail)
+	p.ring[i] = key
+	p.live[i] = true
+	p.index[key] = p.tail
+	p.tail++
 }
 
 func (p *FIFO[K]) OnDel(key K) {
-	for i, k := range p.keys {
-		if k == key {
-			p.keys = append(p.keys[:i], p.keys[i+1:]...)
-			break
-		}
+	pos, ok := p.index[key]
+	if !ok {
+		return
+	}
+	delete(p.index, key)
+	p.clearSlot(pos)
+	p.holes++
+	p.skipHoles()
+
+	if p.holes > len(p.index) {
+		p.compact()
 	}
 }
 
 func (p *FIFO[K]) Evict() (K, bool) {
-	if len(p.keys) == 0 {
-		return *new(K), false
+	if len(p.index) == 0 {
+		var zero K
+		return zero, false
+	}
+	// head is never a hole, see skipHoles
+	key := p.ring[p.slot(p.head)]
+	delete(p.index, key)
+	p.clearSlot(p.head)
+	p.head++
+	p.skipHoles()
+
+	if len(p.ring) > minRingSize && len(p.index) < len(p.ring)/4 {
+		p.compact()
 	}
-	evictedKey := p.keys[0]
-	p.keys = p.keys[1:]
-	return evictedKey, true
+	return key, true
 }
 
 func (p *FIFO[K]) Reset() {
-	clear(p.keys)
+	p.ring = make([]K, minRingSize)
+	p.live = make([]bool, minRingSize)
+	clear(p.index)
+	p.head, p.tail, p.holes = 0, 0, 0
 }
 
 func (p *FIFO[K]) Equals(o Policy[any]) bool {
 	pPtype, pKtype := p.Type()
-	oPtype, oKtype := p.Type()
+	oPtype, oKtype := o.Type()
 	return pPtype == oPtype && pKtype == oKtype
 }
 
 func (p *FIFO[K]) Len() int {
-	return len(p.keys)
+	return len(p.index)
+}
+
+// KeyOverhead estimates the bytes spent per key: its ring slot (the ring is
+// up to twice the number of keys) and index entry.
+func (p *FIFO[K]) KeyOverhead() int64 {
+	return 2*(sizer.Static[K]()+1) + sizer.MapEntry[K, int]()
+}
+
+func (p *FIFO[K]) slot(pos int) int {
+	return pos & (len(p.ring) - 1)
+}
+
+func (p *FIFO[K]) clearSlot(pos int) {
+	i := p.slot(pos)
+	var zero K
+	p.ring[i] = zero
+	p.live[i] = false
+}
+
+// skipHoles moves head past deleted keys, so head is either a key or tail.
+func (p *FIFO[K]) skipHoles() {
+	for p.head < p.tail && !p.live[p.slot(p.head)] {
+		p.head++
+		p.holes--
+	}
+}
+
+// compact repacks the keys into a ring that fits them with room to grow.
+func (p *FIFO[K]) compact() {
+	p.resize(max(minRingSize, nextPow2(2*len(p.index))))
+}
+
+// resize copies the keys in order into a ring of size n (a power of 2) and
+// rebases their positions, dropping all holes.
+func (p *FIFO[K]) resize(n int) {
+	ring := make([]K, n)
+	live := make([]bool, n)
+	j := 0
+	for pos := p.head; pos < p.tail; pos++ {
+		i := p.slot(pos)
+		if !p.live[i] {
+			continue
+		}
+		ring[j] = p.ring[i]
+		live[j] = true
+		p.index[ring[j]] = j
+		j++
+	}
+	p.ring, p.live = ring, live
+	p.head, p.tail, p.holes = 0, j, 0
+}
+
+func (p *FIFO[K]) validate() error {
+	count, holes := 0, 0
+	for pos := p.head; pos < p.tail; pos++ {
+		i := p.slot(pos)
+		if !p.live[i] {
+			holes++
+			continue
+		}
+		if j, ok := p.index[p.ring[i]]; !ok || j != pos {
+			return fmt.Errorf("key '%v' at %d not in index", p.ring[i], pos)
+		}
+		count++
+	}
+	if count != len(p.index) {
+		return fmt.Errorf("len(index): %d != len(queue): %d", len(p.index), count)
+	}
+	if holes != p.holes {
+		return fmt.Errorf("holes: %d != counted: %d", p.holes, holes)
+	}
+	if p.tail-p.head > len(p.ring) {
+		return fmt.Errorf("queue len %d > ring %d", p.tail-p.head, len(p.ring))
+	}
+	return nil
 }
@@ -0,0 +1,118 @@
+package policies
+
+import (
+	"testing"
+)
+
+const benchEntries = 1_000_000
+
+// sliceFIFO is the previous slice backed FIFO, kept as a baseline.
+type sliceFIFO[K comparable] struct {
+	keys []K
+}
+
+func (p *sliceFIFO[K]) OnSet(k K) {
+	p.keys = append(p.keys, k)
+}
+
+func (p *sliceFIFO[K]) OnDel(k K) {
+	for i, key := range p.keys {
+		if key == k {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *sliceFIFO[K]) Evict() (K, bool) {
+	if len(p.keys) == 0 {
+		var zero K
+		return zero, false
+	}
+	k := p.keys[0]
+	p.keys = p.keys[1:]
+	return k, true
+}
+
+func newFullFIFO(n int) *FIFO[int] {
+	p := NewFIFO[int]()
+	for i := range n {
+		p.OnSet(i)
+	}
+	return p
+}
+
+func newFullSliceFIFO(n int) *sliceFIFO[int] {
+	p := &sliceFIFO[int]{keys: make([]int, 0, n)}
+	for i := range n {
+		p.OnSet(i)
+	}
+	return p
+}
+
+func BenchmarkFIFO_OnSet(b *testing.B) {
+	p := newFullFIFO(benchEntries)
+
+	b.ResetTimer()
+	for i := range b.N {
+		p.OnSet(benchEntries + i)
+	}
+}
+
+// Steady state of a full shard: every set evicts the oldest key.
+func BenchmarkFIFO_SetEvict(b *testing.B) {
+	p := newFullFIFO(benchEntries)
+
+	b.ResetTimer()
+	for i := range b.N {
+		p.Evict()
+		p.OnSet(benchEntries + i)
+	}
+}
+
+func BenchmarkSliceFIFO_SetEvict(b *testing.B) {
+	p := newFullSliceFIFO(benchEntries)
+
+	b.ResetTimer()
+	for i := range b.N {
+		p.Evict()
+		p.OnSet(benchEntries + i)
+	}
+}
+
+// Deletes keys from the middle of the queue and sets them again, so the
+// queue stays at benchEntries.
+func BenchmarkFIFO_OnDel(b *testing.B) {
+	p := newFullFIFO(benchEntries)
+
+	b.ResetTimer()
+	for i := range b.N {
+		k := benchEntries/2 + i%(benchEntries/2)
+		p.OnDel(k)
+		p.OnSet(k)
+	}
+}
+
+func BenchmarkSliceFIFO_OnDel(b *testing.B) {
+	p := newFullSliceFIFO(benchEntries)
+
+	b.ResetTimer()
+	for i := range b.N {
+		k := benchEntries/2 + i%(benchEntries/2)
+		p.OnDel(k)
+		p.OnSet(k)
+	}
+}
+
+func BenchmarkFIFO_Evict(b *testing.B) {
+	p := newFullFIFO(benchEntries)
+
+	b.ResetTimer()
+	for range b.N {
+		if _, ok := p.Evict(); !ok {
+			b.StopTimer()
+			p = newFullFIFO(benchEntries)
+			b.StartTimer()
+		}
+	}
+}
@@ -4,13 +4,27 @@ import (
 	"testing"
 )
 
+// keys returns the queue in insertion order.
+func (p *FIFO[K]) keys() []K {
+	var keys []K
+	for pos := p.head; pos < p.tail; pos++ {
+		if i := p.slot(pos); p.live[i] {
+			keys = append(keys, p.ring[i])
+		}
+	}
+	return keys
+}
+
 func TestNewFIFO(t *testing.T) {
 	p := NewFIFO[string]()
-	if p.keys == nil {
-		t.Fatal("Expected 'keys' to be set, got nil")
+	if p.index == nil {
+		t.Fatal("Expected 'index' to be set, got nil")
+	}
+	if len(p.ring) != minRingSize {
+		t.Errorf("Expected %d, got %d", minRingSize, len(p.ring))
 	}
-	if len(p.keys) != 0 {
-		t.Errorf("Expected 0, got %d", len(p.keys))
+	if p.Len() != 0 {
+		t.Errorf("Expected 0, got %d", p.Len())
 	}
 }
 
@@ -29,44 +43,66 @@ func TestFIFO_Type(t *testing.T) {
 func TestFIFO_OnHit(t *testing.T) {
 	p := NewFIFO[string]()
 	p.OnHit("")
-	if len(p.keys) != 0 {
-		t.Errorf("Expected 0, got %d", len(p.keys))
+	if p.Len() != 0 {
+		t.Errorf("Expected 0, got %d", p.Len())
 	}
 }
 
 func TestFIFO_OnSet(t *testing.T) {
 	p := NewFIFO[string]()
 	p.OnSet("one")
-	if len(p.keys) != 1 {
-		t.Fatalf("Expected 1, got %d", len(p.keys))
+	keys := p.keys()
+	if len(keys) != 1 {
+		t.Fatalf("Expected 1, got %d", len(keys))
 	}
-	if p.keys[0] != "one" {
-		t.Errorf("Expected 'one', got %s", p.keys[0])
+	if keys[0] != "one" {
+		t.Errorf("Expected 'one', got %s", keys[0])
 	}
 	p.OnSet("two")
-	if len(p.keys) != 2 {
-		t.Fatalf("Expected 1, got %d", len(p.keys))
+	keys = p.keys()
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2, got %d", len(keys))
 	}
-	if p.keys[1] != "two" {
-		t.Errorf("Expected 'two', got %s", p.keys[1])
+	if keys[1] != "two" {
+		t.Errorf("Expected 'two', got %s", keys[1])
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
 	}
 }
 
 func TestFIFO_OnSet_Repeat(t *testing.T) {
 	p := NewFIFO[string]()
 	p.OnSet("one")
-	if len(p.keys) != 1 {
-		t.Fatalf("Expected 1, got %d", len(p.keys))
+	p.OnSet("two")
+	p.OnSet("one") // keeps its position
+	keys := p.keys()
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2, got %d", len(keys))
 	}
-	if p.keys[0] != "one" {
-		t.Errorf("Expected 'one', got %s", p.keys[0])
+	if keys[0] != "one" {
+		t.Errorf("Expected 'one', got %s", keys[0])
 	}
-	p.OnSet("one")
-	if len(p.keys) != 2 {
-		t.Fatalf("Expected 1, got %d", len(p.keys))
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestFIFO_OnSet_Grow(t *testing.T) {
+	p := NewFIFO[int]()
+	for i := range 3 * minRingSize {
+		p.OnSet(i)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := len(p.ring); l != 4*minRingSize {
+		t.Errorf("expected ring=%d, got %d", 4*minRingSize, l)
 	}
-	if p.keys[1] != "one" {
-		t.Errorf("Expected 'one', got %s", p.keys[1])
+	for i := range 3 * minRingSize {
+		if k, _ := p.Evict(); k != i {
+			t.Fatalf("expected k=%d, got %d", i, k)
+		}
 	}
 }
 
@@ -74,8 +110,11 @@ func TestFIFO_OnDel(t *testing.T) {
 	p := NewFIFO[string]()
 	p.OnSet("one")
 	p.OnDel("one")
-	if len(p.keys) != 0 {
-		t.Fatalf("Expected 0, got %d", len(p.keys))
+	if p.Len() != 0 {
+		t.Fatalf("Expected 0, got %d", p.Len())
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
 	}
 }
 
@@ -84,11 +123,63 @@ func TestFIFO_OnDel_MultipleValues(t *testing.T) {
 	p.OnSet(1)
 	p.OnSet(2)
 	p.OnDel(2)
-	if len(p.keys) != 1 {
-		t.Fatalf("Expected 1, got %d", len(p.keys))
+	keys := p.keys()
+	if len(keys) != 1 {
+		t.Fatalf("Expected 1, got %d", len(keys))
 	}
-	if p.keys[0] != 1 {
-		t.Errorf("expected 1, got %d", p.keys[0])
+	if keys[0] != 1 {
+		t.Errorf("expected 1, got %d", keys[0])
+	}
+}
+
+func TestFIFO_OnDel_Holes(t *testing.T) {
+	p := NewFIFO[int]()
+	for i := range 6 {
+		p.OnSet(i)
+	}
+	p.OnDel(1)
+	p.OnDel(3)
+	if p.holes != 2 {
+		t.Fatalf("expected holes=2, got %d", p.holes)
+	}
+
+	// head is deleted: head moves past the hole behind it
+	p.OnDel(0)
+	if p.holes != 1 {
+		t.Errorf("expected holes=1, got %d", p.holes)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+
+	// evict skips holes
+	for _, want := range []int{2, 4, 5} {
+		if k, _ := p.Evict(); k != want {
+			t.Errorf("expected k=%d, got %d", want, k)
+		}
+	}
+	if p.holes != 0 {
+		t.Errorf("expected holes=0, got %d", p.holes)
+	}
+}
+
+func TestFIFO_OnDel_Compact(t *testing.T) {
+	p := NewFIFO[int]()
+	for i := range 1000 {
+		p.OnSet(i)
+	}
+	for i := range 999 {
+		p.OnDel(i + 1)
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := len(p.ring); l != minRingSize {
+		t.Errorf("expected ring=%d, got %d", minRingSize, l)
+	}
+	if k, _ := p.Evict(); k != 0 {
+		t.Errorf("expected k=0, got %d", k)
 	}
 }
 
@@ -103,11 +194,12 @@ func TestFIFO_Evict(t *testing.T) {
 	if k != 1 {
 		t.Errorf("expected k=1, got %d", k)
 	}
-	if len(p.keys) != 1 {
-		t.Fatalf("expected len=1, got %d", len(p.keys))
+	keys := p.keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected len=1, got %d", len(keys))
 	}
-	if p.keys[0] != 2 {
-		t.Errorf("expected [0]=2, got %d", p.keys[0])
+	if keys[0] != 2 {
+		t.Errorf("expected [0]=2, got %d", keys[0])
 	}
 }
 
@@ -119,6 +211,44 @@ func TestFIFO_Evict_Empty(t *testing.T) {
 	}
 }
 
+func TestFIFO_Evict_Shrink(t *testing.T) {
+	p := NewFIFO[int]()
+	for i := range 1024 {
+		p.OnSet(i)
+	}
+	for range 1000 {
+		p.Evict()
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := len(p.ring); l > 128 {
+		t.Errorf("expected ring <= 128, got %d", l)
+	}
+	if k, _ := p.Evict(); k != 1000 {
+		t.Errorf("expected k=1000, got %d", k)
+	}
+}
+
+func TestFIFO_Reset(t *testing.T) {
+	p := NewFIFO[int]()
+	for i := range 100 {
+		p.OnSet(i)
+	}
+	p.Reset()
+
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if l := len(p.ring); l != minRingSize {
+		t.Errorf("expected ring=%d, got %d", minRingSize, l)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Errorf("expected evict=false, got true")
+	}
+}
+
 func TestFIFO_Len(t *testing.T) {
 	p := NewFIFO[int]()
 	p.OnSet(1)
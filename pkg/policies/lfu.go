@@ -0,0 +1,255 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+// minAgingInterval is the minimum number of hits between two aging steps.
+const minAgingInterval = 1024
+
+// agingFactor scales the aging interval with the number of tracked keys, so
+// the O(n) halving step stays amortized O(1) per hit.
+const agingFactor = 8
+
+type lfuEntry[K comparable] struct {
+	key        K
+	bucket     *lfuBucket[K]
+	prev, next *lfuEntry[K]
+}
+
+// lfuBucket holds all keys with the same frequency, most recently used first.
+type lfuBucket[K comparable] struct {
+	freq       int
+	head, tail *lfuEntry[K]
+	prev, next *lfuBucket[K]
+}
+
+// LFU evicts the least frequently used key, breaking ties by recency.
+// Keys are kept in frequency buckets ordered ascending, so OnHit, OnSet and
+// Evict are O(1). Every so often all frequencies are halved, which lets keys
+// that were popular a long time ago age out.
+type LFU[K comparable] struct {
+	entries map[K]*lfuEntry[K]
+	head    *lfuBucket[K] // lowest frequency
+	hits    int
+	// hits between two aging steps, 0 scales with the number of keys
+	agingInterval int
+}
+
+func NewLFU[K comparable]() *LFU[K] {
+	return &LFU[K]{entries: make(map[K]*lfuEntry[K])}
+}
+
+func (p *LFU[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeLFU, t
+}
+
+// fails silently when key is not in policy
+func (p *LFU[K]) OnHit(k K) {
+	e := p.entries[k]
+	if e == nil {
+		return
+	}
+	p.increment(e)
+
+	p.hits++
+	if p.hits >= p.interval() {
+		p.age()
+	}
+}
+
+func (p *LFU[K]) OnSet(k K) {
+	if _, ok := p.entries[k]; ok {
+		p.OnHit(k)
+		return
+	}
+	e := &lfuEntry[K]{key: k}
+	p.entries[k] = e
+
+	b := p.head
+	if b == nil || b.freq != 1 {
+		b = &lfuBucket[K]{freq: 1}
+		p.insertBucketAfter(b, nil)
+	}
+	b.pushFront(e)
+}
+
+func (p *LFU[K]) OnDel(k K) {
+	if e := p.entries[k]; e != nil {
+		p.unlink(e)
+		delete(p.entries, k)
+	}
+}
+
+func (p *LFU[K]) Evict() (K, bool) {
+	if p.head == nil {
+		var zero K
+		return zero, false
+	}
+	e := p.head.tail
+	p.unlink(e)
+	delete(p.entries, e.key)
+	return e.key, true
+}
+
+func (p *LFU[K]) Reset() {
+	clear(p.entries)
+	p.head = nil
+	p.hits = 0
+}
+
+func (p *LFU[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+func (p *LFU[K]) Len() int {
+	return len(p.entries)
+}
+
+// KeyOverhead estimates the bytes spent per key: its entry and map entry.
+// Buckets are shared between keys and not counted.
+func (p *LFU[K]) KeyOverhead() int64 {
+	return sizer.Static[lfuEntry[K]]() + sizer.MapEntry[K, *lfuEntry[K]]()
+}
+
+// increment moves e to the bucket with frequency+1, creating it if needed.
+func (p *LFU[K]) increment(e *lfuEntry[K]) {
+	cur := e.bucket
+	next := cur.next
+	if next == nil || next.freq != cur.freq+1 {
+		next = &lfuBucket[K]{freq: cur.freq + 1}
+		p.insertBucketAfter(next, cur)
+	}
+	p.unlink(e)
+	next.pushFront(e)
+}
+
+// unlink removes e from its bucket and drops the bucket when it becomes empty.
+func (p *LFU[K]) unlink(e *lfuEntry[K]) {
+	b := e.bucket
+	b.remove(e)
+	if b.head == nil {
+		p.removeBucket(b)
+	}
+}
+
+func (p *LFU[K]) interval() int {
+	if p.agingInterval > 0 {
+		return p.agingInterval
+	}
+	return max(minAgingInterval, agingFactor*len(p.entries))
+}
+
+// age halves the frequency of every key (never below 1). Buckets are walked
+// in ascending order, so the halved frequencies stay sorted and buckets that
+// end up with the same frequency are merged with the colder keys at the tail.
+func (p *LFU[K]) age() {
+	p.hits = 0
+
+	old := p.head
+	p.head = nil
+	var last *lfuBucket[K]
+	for b := old; b != nil; b = b.next {
+		freq := max(1, b.freq/2)
+		if last == nil || last.freq != freq {
+			nb := &lfuBucket[K]{freq: freq}
+			p.insertBucketAfter(nb, last)
+			last = nb
+		}
+		for e := b.tail; e != nil; {
+			prev := e.prev
+			e.prev, e.next = nil, nil
+			last.pushFront(e)
+			e = prev
+		}
+	}
+}
+
+// insertBucketAfter links b after prev, or at the front when prev is nil.
+func (p *LFU[K]) insertBucketAfter(b, prev *lfuBucket[K]) {
+	if prev == nil {
+		b.next = p.head
+		if p.head != nil {
+			p.head.prev = b
+		}
+		p.head = b
+		return
+	}
+	b.prev = prev
+	b.next = prev.next
+	if prev.next != nil {
+		prev.next.prev = b
+	}
+	prev.next = b
+}
+
+func (p *LFU[K]) removeBucket(b *lfuBucket[K]) {
+	if b.prev == nil {
+		p.head = b.next
+	} else {
+		b.prev.next = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	}
+	b.prev, b.next = nil, nil
+}
+
+func (b *lfuBucket[K]) pushFront(e *lfuEntry[K]) {
+	e.bucket = b
+	e.prev = nil
+	e.next = b.head
+	if b.head != nil {
+		b.head.prev = e
+	} else {
+		b.tail = e
+	}
+	b.head = e
+}
+
+func (b *lfuBucket[K]) remove(e *lfuEntry[K]) {
+	if e.prev == nil {
+		b.head = e.next
+	} else {
+		e.prev.next = e.next
+	}
+	if e.next == nil {
+		b.tail = e.prev
+	} else {
+		e.next.prev = e.prev
+	}
+	e.prev, e.next, e.bucket = nil, nil, nil
+}
+
+func (p *LFU[K]) validate() error {
+	count := 0
+	prevFreq := 0
+	for b := p.head; b != nil; b = b.next {
+		if b.freq <= prevFreq {
+			return fmt.Errorf("bucket freq %d after %d", b.freq, prevFreq)
+		}
+		if b.head == nil {
+			return fmt.Errorf("empty bucket with freq %d", b.freq)
+		}
+		prevFreq = b.freq
+		for e := b.head; e != nil; e = e.next {
+			if n, ok := p.entries[e.key]; !ok || n != e {
+				return fmt.Errorf("entry '%v' not in map", e.key)
+			}
+			if e.bucket != b {
+				return fmt.Errorf("entry '%v' in wrong bucket", e.key)
+			}
+			count++
+		}
+	}
+	if len(p.entries) != count {
+		return fmt.Errorf("len(map): %d != len(buckets): %d", len(p.entries), count)
+	}
+	return nil
+}
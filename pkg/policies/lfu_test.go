@@ -0,0 +1,252 @@
+package policies
+
+import "testing"
+
+func TestNewLFU(t *testing.T) {
+	p := NewLFU[int]()
+	if p.entries == nil {
+		t.Fatalf("expected entries to be set, got nil")
+	}
+	if len(p.entries) != 0 {
+		t.Errorf("expected len=0, got %d", len(p.entries))
+	}
+	if p.head != nil {
+		t.Errorf("expected head=nil, got %v", p.head)
+	}
+}
+
+func TestLFU_Type(t *testing.T) {
+	p := NewLFU[int]()
+	ptype, ktype := p.Type()
+	if ptype != TypeLFU {
+		t.Errorf("expected 'LFU', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestLFU_OnSet(t *testing.T) {
+	p := NewLFU[int]()
+	for i := range 10 {
+		p.OnSet(i)
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.head.freq != 1 {
+		t.Errorf("expected freq=1, got %d", p.head.freq)
+	}
+	if p.head.next != nil {
+		t.Errorf("expected single bucket, got %v", p.head.next)
+	}
+	if p.head.head.key != 9 {
+		t.Errorf("expected key=9 in front, got %d", p.head.head.key)
+	}
+}
+
+func TestLFU_OnSet_Duplicate(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(1)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := p.Len(); l != 1 {
+		t.Fatalf("expected len=1, got %d", l)
+	}
+	if f := p.entries[1].bucket.freq; f != 2 {
+		t.Errorf("expected freq=2, got %d", f)
+	}
+}
+
+func TestLFU_OnHit(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	p.OnHit(1)
+	p.OnHit(1)
+	p.OnHit(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if f := p.entries[1].bucket.freq; f != 3 {
+		t.Errorf("expected freq=3, got %d", f)
+	}
+	if f := p.entries[2].bucket.freq; f != 2 {
+		t.Errorf("expected freq=2, got %d", f)
+	}
+	if f := p.entries[3].bucket.freq; f != 1 {
+		t.Errorf("expected freq=1, got %d", f)
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(4)
+	if l := p.Len(); l != 3 {
+		t.Errorf("expected len=3, got %d", l)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestLFU_OnDel(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(2)
+
+	p.OnDel(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := p.Len(); l != 1 {
+		t.Fatalf("expected len=1, got %d", l)
+	}
+	// bucket for freq=2 should be dropped
+	if p.head.next != nil {
+		t.Errorf("expected single bucket, got %v", p.head.next)
+	}
+
+	p.OnDel(3) // not in policy
+	if l := p.Len(); l != 1 {
+		t.Fatalf("expected len=1, got %d", l)
+	}
+}
+
+func TestLFU_Evict(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	p.OnHit(1)
+	p.OnHit(3)
+
+	// 2 is least frequently used
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 2 {
+		t.Errorf("expected k=2, got %d", k)
+	}
+
+	// 1 and 3 share a frequency, 1 was used least recently
+	k, _ = p.Evict()
+	if k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if l := p.Len(); l != 1 {
+		t.Errorf("expected len=1, got %d", l)
+	}
+}
+
+func TestLFU_Evict_Empty(t *testing.T) {
+	p := NewLFU[int]()
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestLFU_age(t *testing.T) {
+	p := NewLFU[int]()
+	p.agingInterval = 1 << 30 // age manually
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	for range 9 {
+		p.OnHit(1) // freq=10
+	}
+	for range 2 {
+		p.OnHit(2) // freq=3
+	}
+	p.OnHit(3) // freq=2
+
+	p.age()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if f := p.entries[1].bucket.freq; f != 5 {
+		t.Errorf("expected freq=5, got %d", f)
+	}
+	if f := p.entries[2].bucket.freq; f != 1 {
+		t.Errorf("expected freq=1, got %d", f)
+	}
+	if f := p.entries[3].bucket.freq; f != 1 {
+		t.Errorf("expected freq=1, got %d", f)
+	}
+	// 3 was colder than 2 before aging, so it should go first
+	if k, _ := p.Evict(); k != 3 {
+		t.Errorf("expected k=3, got %d", k)
+	}
+	if p.hits != 0 {
+		t.Errorf("expected hits=0, got %d", p.hits)
+	}
+}
+
+func TestLFU_age_Interval(t *testing.T) {
+	p := NewLFU[int]()
+	p.agingInterval = 4
+	p.OnSet(1)
+	p.OnSet(2)
+	for range 4 {
+		p.OnHit(1) // freq=5, aged to 2 on the 4th hit
+	}
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if f := p.entries[1].bucket.freq; f != 2 {
+		t.Errorf("expected freq=2, got %d", f)
+	}
+
+	// old popular key should eventually be evicted once others catch up
+	for range 3 {
+		p.OnHit(2)
+	}
+	if k, _ := p.Evict(); k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+}
+
+func TestLFU_Reset(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(1)
+
+	p.Reset()
+
+	if l := p.Len(); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Errorf("expected ok=false, got true")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestLFU_Len(t *testing.T) {
+	p := NewLFU[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if p.Len() != 3 {
+		t.Errorf("expected 3, got %d", p.Len())
+	}
+}
@@ -0,0 +1,91 @@
+package policies
+
+import "fmt"
+
+// keyNode is an element of a keyList. It remembers the list it is in, so
+// policies that juggle several lists can tell where a key lives.
+type keyNode[K comparable] struct {
+	key        K
+	prev, next *keyNode[K]
+	list       *keyList[K]
+	// access counter for policies that don't reorder on hit
+	freq uint8
+}
+
+// keyList is an intrusive doubly linked list of keys, head is most recent.
+type keyList[K comparable] struct {
+	head, tail *keyNode[K]
+	len        int
+}
+
+func (l *keyList[K]) pushFront(n *keyNode[K]) {
+	n.list = l
+	n.prev = nil
+	n.next = l.head
+	if l.head != nil {
+		l.head.prev = n
+	} else {
+		l.tail = n
+	}
+	l.head = n
+	l.len++
+}
+
+func (l *keyList[K]) remove(n *keyNode[K]) {
+	if n.prev == nil {
+		l.head = n.next
+	} else {
+		n.prev.next = n.next
+	}
+	if n.next == nil {
+		l.tail = n.prev
+	} else {
+		n.next.prev = n.prev
+	}
+	n.prev, n.next, n.list = nil, nil, nil
+	l.len--
+}
+
+func (l *keyList[K]) moveToFront(n *keyNode[K]) {
+	if l.head == n {
+		return
+	}
+	l.remove(n)
+	l.pushFront(n)
+}
+
+// back returns the least recent node, or nil when the list is empty.
+func (l *keyList[K]) back() *keyNode[K] {
+	return l.tail
+}
+
+func (l *keyList[K]) reset() {
+	l.head, l.tail = nil, nil
+	l.len = 0
+}
+
+// validate checks the links of the list and that every node is in nodes.
+func (l *keyList[K]) validate(nodes map[K]*keyNode[K]) error {
+	count := 0
+	var prev *keyNode[K]
+	for n := l.head; n != nil; n = n.next {
+		if n.prev != prev {
+			return fmt.Errorf("node '%v' has broken prev link", n.key)
+		}
+		if n.list != l {
+			return fmt.Errorf("node '%v' in wrong list", n.key)
+		}
+		if m, ok := nodes[n.key]; !ok || m != n {
+			return fmt.Errorf("node '%v' not in map", n.key)
+		}
+		prev = n
+		count++
+	}
+	if l.tail != prev {
+		return fmt.Errorf("tail is not last node")
+	}
+	if l.len != count {
+		return fmt.Errorf("len: %d != count: %d", l.len, count)
+	}
+	return nil
+}
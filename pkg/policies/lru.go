@@ -3,6 +3,8 @@ package policies
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
 )
 
 type Node[K comparable] struct {
@@ -125,6 +127,11 @@ func (p LRU[K]) Len() int {
 	return count
 }
 
+// KeyOverhead estimates the bytes spent per key: its node and map entry.
+func (p LRU[K]) KeyOverhead() int64 {
+	return sizer.Static[Node[K]]() + sizer.MapEntry[K, *Node[K]]()
+}
+
 func (p *LRU[K]) Equals(o Policy[any]) bool {
 	pPtype, pKtype := p.Type()
 	oPtype, oKtype := p.Type()
@@ -0,0 +1,110 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/jeltjongsma/go-cache/pkg/hasher"
+)
+
+// PolicyFactory returns a new policy. The cache calls it once per shard, so
+// every shard gets its own instance.
+type PolicyFactory[K comparable] func() Policy[K]
+
+// Config describes the shard a policy is built for.
+type Config[K comparable] struct {
+	Capacity int // capacity of the shard, 0 means no limit
+	Hasher   *hasher.Hasher[K]
+}
+
+// Constructor builds a policy for a shard, it is what gets registered under
+// a PolicyType.
+type Constructor[K comparable] func(cfg Config[K]) Policy[K]
+
+type registryKey struct {
+	ptype PolicyType
+	ktype reflect.Type
+}
+
+var registry = struct {
+	sync.RWMutex
+	m map[registryKey]any
+}{m: make(map[registryKey]any)}
+
+// Register makes a custom policy available under t for caches with key type
+// K, so it can be selected by name like the built-in policies.
+// Returns an error when t is a built-in type or already registered for K.
+func Register[K comparable](t PolicyType, c Constructor[K]) error {
+	if c == nil {
+		return errors.New("constructor must not be nil")
+	}
+	if _, ok := builtin[K](t); ok {
+		return fmt.Errorf("policy type %s is built in", t)
+	}
+	key := registryKey{t, reflect.TypeOf((*K)(nil)).Elem()}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.m[key]; ok {
+		return fmt.Errorf("policy type %s already registered for %s", t, key.ktype)
+	}
+	registry.m[key] = c
+	return nil
+}
+
+// Unregister removes the policy registered under t for key type K.
+func Unregister[K comparable](t PolicyType) {
+	key := registryKey{t, reflect.TypeOf((*K)(nil)).Elem()}
+
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.m, key)
+}
+
+// Lookup returns the constructor for t, either a built-in policy or one
+// registered for key type K.
+func Lookup[K comparable](t PolicyType) (Constructor[K], bool) {
+	if c, ok := builtin[K](t); ok {
+		return c, true
+	}
+	key := registryKey{t, reflect.TypeOf((*K)(nil)).Elem()}
+
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.m[key]
+	if !ok {
+		return nil, false
+	}
+	return c.(Constructor[K]), true
+}
+
+func builtin[K comparable](t PolicyType) (Constructor[K], bool) {
+	switch t {
+	case TypeFIFO:
+		return func(Config[K]) Policy[K] { return NewFIFO[K]() }, true
+	case TypeLRU:
+		return func(Config[K]) Policy[K] { return NewLRU[K]() }, true
+	case TypeLFU:
+		return func(Config[K]) Policy[K] { return NewLFU[K]() }, true
+	case TypeARC:
+		return func(cfg Config[K]) Policy[K] { return NewARC[K](cfg.Capacity) }, true
+	case TypeWTinyLFU:
+		return func(cfg Config[K]) Policy[K] { return NewWTinyLFU(cfg.Capacity, cfg.Hasher) }, true
+	case TypeSIEVE:
+		return func(Config[K]) Policy[K] { return NewSIEVE[K]() }, true
+	case TypeS3FIFO:
+		return func(cfg Config[K]) Policy[K] { return NewS3FIFO[K](cfg.Capacity) }, true
+	case TypeCLOCK:
+		return func(cfg Config[K]) Policy[K] { return NewCLOCK[K](cfg.Capacity) }, true
+	case TypeCLOCKPro:
+		return func(cfg Config[K]) Policy[K] { return NewCLOCKPro[K](cfg.Capacity) }, true
+	case TypeSampledLRU:
+		return func(Config[K]) Policy[K] { return NewSampledLRU[K](DefaultSamples) }, true
+	case TypeSampledLFU:
+		return func(Config[K]) Policy[K] { return NewSampledLFU[K](DefaultSamples) }, true
+	default:
+		return nil, false
+	}
+}
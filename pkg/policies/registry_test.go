@@ -0,0 +1,89 @@
+package policies
+
+import "testing"
+
+func TestLookup_Builtin(t *testing.T) {
+	types := []PolicyType{
+		TypeFIFO, TypeLRU, TypeLFU, TypeARC, TypeWTinyLFU,
+		TypeSIEVE, TypeS3FIFO, TypeCLOCK, TypeCLOCKPro,
+		TypeSampledLRU, TypeSampledLFU,
+	}
+	for _, typ := range types {
+		c, ok := Lookup[int](typ)
+		if !ok {
+			t.Fatalf("expected %s to be built in", typ)
+		}
+		p := c(Config[int]{Capacity: 10})
+		if ptype, _ := p.Type(); ptype != typ {
+			t.Errorf("expected %s, got %s", typ, ptype)
+		}
+		// every call returns a new instance
+		if c(Config[int]{Capacity: 10}) == p {
+			t.Errorf("%s: expected new instance", typ)
+		}
+	}
+
+	if _, ok := Lookup[int]("unknown"); ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const custom PolicyType = "custom"
+	newLRU := func(Config[int]) Policy[int] { return NewLRU[int]() }
+
+	if err := Register(custom, newLRU); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { Unregister[int](custom) })
+
+	c, ok := Lookup[int](custom)
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if ptype, _ := c(Config[int]{}).Type(); ptype != TypeLRU {
+		t.Errorf("expected LRU, got %s", ptype)
+	}
+
+	// registered per key type
+	if _, ok := Lookup[string](custom); ok {
+		t.Errorf("expected ok=false for string keys, got true")
+	}
+
+	if err := Register(custom, newLRU); err == nil {
+		t.Errorf("expected error on duplicate, got nil")
+	}
+	if err := Register(TypeFIFO, newLRU); err == nil {
+		t.Errorf("expected error on built-in type, got nil")
+	}
+	if err := Register[int]("nil", nil); err == nil {
+		t.Errorf("expected error on nil constructor, got nil")
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	const custom PolicyType = "custom"
+	Register(custom, func(Config[int]) Policy[int] { return NewFIFO[int]() })
+	Unregister[int](custom)
+
+	if _, ok := Lookup[int](custom); ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestKeyOverhead(t *testing.T) {
+	types := []PolicyType{
+		TypeFIFO, TypeLRU, TypeLFU, TypeARC, TypeWTinyLFU,
+		TypeSIEVE, TypeS3FIFO, TypeCLOCK, TypeCLOCKPro,
+	}
+	for _, typ := range types {
+		c, _ := Lookup[int](typ)
+		r, ok := c(Config[int]{Capacity: 10}).(MemoryReporter)
+		if !ok {
+			t.Fatalf("%s: expected MemoryReporter", typ)
+		}
+		if o := r.KeyOverhead(); o <= 8 {
+			t.Errorf("%s: expected overhead > 8, got %d", typ, o)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+const (
+	smallPercent = 10
+	maxFreq      = 3
+)
+
+// S3FIFO uses three FIFO queues: a small probationary queue, a main queue
+// and a ghost queue of recently evicted keys. New keys enter small; keys
+// that were hit while in small move to main when they reach its end, the
+// others are evicted and remembered as ghosts. A ghost that is set again
+// goes straight to main. Main is a FIFO with reinsertion: keys with a non
+// zero counter get another round. Like SIEVE, hits only bump a counter.
+type S3FIFO[K comparable] struct {
+	small, main, ghost *keyList[K]
+	nodes              map[K]*keyNode[K]
+	cap                int
+	smallCap, ghostCap int // only used with a capacity
+}
+
+// NewS3FIFO returns an S3-FIFO policy for a shard that holds at most cap
+// keys. cap sizes the small and ghost queues. With cap == 0 (a shard bounded
+// by cost or memory) they are sized by the number of resident keys instead.
+func NewS3FIFO[K comparable](cap int) *S3FIFO[K] {
+	cap = max(cap, 0)
+	smallCap := max(1, cap*smallPercent/100)
+	return &S3FIFO[K]{
+		small:    &keyList[K]{},
+		main:     &keyList[K]{},
+		ghost:    &keyList[K]{},
+		nodes:    make(map[K]*keyNode[K]),
+		cap:      cap,
+		smallCap: smallCap,
+		ghostCap: max(0, cap-smallCap),
+	}
+}
+
+func (p *S3FIFO[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeS3FIFO, t
+}
+
+// fails silently when key is not resident
+func (p *S3FIFO[K]) OnHit(k K) {
+	if n := p.nodes[k]; n != nil && n.list != p.ghost {
+		n.freq = min(n.freq+1, maxFreq)
+	}
+}
+
+func (p *S3FIFO[K]) OnSet(k K) {
+	if n := p.nodes[k]; n != nil {
+		if n.list != p.ghost {
+			p.OnHit(k)
+			return
+		}
+		p.ghost.remove(n)
+		p.main.pushFront(n)
+		return
+	}
+	n := &keyNode[K]{key: k}
+	p.nodes[k] = n
+	p.small.pushFront(n)
+}
+
+func (p *S3FIFO[K]) OnDel(k K) {
+	if n := p.nodes[k]; n != nil {
+		n.list.remove(n)
+		delete(p.nodes, k)
+	}
+}
+
+func (p *S3FIFO[K]) Evict() (K, bool) {
+	if p.small.len > 0 && (p.small.len >= p.smallLimit() || p.main.len == 0) {
+		if n := p.evictSmall(); n != nil {
+			return n.key, true
+		}
+	}
+	if n := p.evictMain(); n != nil {
+		return n.key, true
+	}
+	var zero K
+	return zero, false
+}
+
+func (p *S3FIFO[K]) Reset() {
+	p.small.reset()
+	p.main.reset()
+	p.ghost.reset()
+	clear(p.nodes)
+}
+
+func (p *S3FIFO[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+// Len returns the number of resident keys (ghosts are not counted).
+func (p *S3FIFO[K]) Len() int {
+	return p.small.len + p.main.len
+}
+
+// KeyOverhead estimates the bytes spent per key: its node and map entry,
+// doubled because the ghost queue tracks about as many keys as are resident.
+func (p *S3FIFO[K]) KeyOverhead() int64 {
+	return 2 * (sizer.Static[keyNode[K]]() + sizer.MapEntry[K, *keyNode[K]]())
+}
+
+func (p *S3FIFO[K]) smallLimit() int {
+	if p.cap > 0 {
+		return p.smallCap
+	}
+	return max(1, p.Len()*smallPercent/100)
+}
+
+func (p *S3FIFO[K]) ghostLimit() int {
+	if p.cap > 0 {
+		return p.ghostCap
+	}
+	return p.Len()
+}
+
+// evictSmall moves accessed keys from the end of small to main and evicts
+// the first one that wasn't accessed. Returns nil when small runs empty.
+func (p *S3FIFO[K]) evictSmall() *keyNode[K] {
+	for p.small.len > 0 {
+		n := p.small.back()
+		p.small.remove(n)
+		if n.freq > 0 {
+			n.freq = 0
+			p.main.pushFront(n)
+			continue
+		}
+		p.ghost.pushFront(n)
+		for p.ghost.len > p.ghostLimit() {
+			g := p.ghost.back()
+			p.ghost.remove(g)
+			delete(p.nodes, g.key)
+		}
+		return n
+	}
+	return nil
+}
+
+// evictMain reinserts keys with a non zero counter (decrementing it) and
+// evicts the first one without. Returns nil when main is empty.
+func (p *S3FIFO[K]) evictMain() *keyNode[K] {
+	for p.main.len > 0 {
+		n := p.main.back()
+		if n.freq > 0 {
+			n.freq--
+			p.main.moveToFront(n)
+			continue
+		}
+		p.main.remove(n)
+		delete(p.nodes, n.key)
+		return n
+	}
+	return nil
+}
+
+func (p *S3FIFO[K]) validate() error {
+	total := 0
+	for _, l := range []*keyList[K]{p.small, p.main, p.ghost} {
+		if err := l.validate(p.nodes); err != nil {
+			return err
+		}
+		total += l.len
+	}
+	if total != len(p.nodes) {
+		return fmt.Errorf("len(map): %d != len(queues): %d", len(p.nodes), total)
+	}
+	// without a capacity the limit follows the resident keys, which may
+	// shrink without trimming the ghosts
+	if p.cap > 0 && p.ghost.len > p.ghostCap {
+		return fmt.Errorf("ghost len %d > cap %d", p.ghost.len, p.ghostCap)
+	}
+	return nil
+}
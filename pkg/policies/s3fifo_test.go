@@ -0,0 +1,219 @@
+package policies
+
+import "testing"
+
+func TestNewS3FIFO(t *testing.T) {
+	p := NewS3FIFO[int](100)
+	if p.nodes == nil {
+		t.Fatalf("expected nodes to be set, got nil")
+	}
+	if p.smallCap != 10 {
+		t.Errorf("expected smallCap=10, got %d", p.smallCap)
+	}
+	if p.ghostCap != 90 {
+		t.Errorf("expected ghostCap=90, got %d", p.ghostCap)
+	}
+}
+
+func TestS3FIFO_Type(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	ptype, ktype := p.Type()
+	if ptype != TypeS3FIFO {
+		t.Errorf("expected 'S3-FIFO', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestS3FIFO_OnSet(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.small.len != 2 {
+		t.Errorf("expected len(small)=2, got %d", p.small.len)
+	}
+
+	p.OnSet(1) // counts as a hit
+	if p.nodes[1].freq != 1 {
+		t.Errorf("expected freq=1, got %d", p.nodes[1].freq)
+	}
+}
+
+func TestS3FIFO_OnHit(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	for range 5 {
+		p.OnHit(1)
+	}
+	if f := p.nodes[1].freq; f != maxFreq {
+		t.Errorf("expected freq=%d, got %d", maxFreq, f)
+	}
+	// hits don't move nodes
+	if n := p.nodes[1]; n.list != p.small {
+		t.Errorf("expected key=1 in small")
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(2)
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+}
+
+func TestS3FIFO_Evict_Small(t *testing.T) {
+	p := NewS3FIFO[int](10) // smallCap=1
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(1)
+
+	// 1 was hit so moves to main, 2 is evicted into ghost
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 2 {
+		t.Errorf("expected k=2, got %d", k)
+	}
+	if n := p.nodes[1]; n.list != p.main || n.freq != 0 {
+		t.Errorf("expected key=1 in main with freq=0")
+	}
+	if n := p.nodes[2]; n == nil || n.list != p.ghost {
+		t.Fatalf("expected key=2 in ghost")
+	}
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+
+	// ghost goes straight to main
+	p.OnSet(2)
+	if n := p.nodes[2]; n.list != p.main {
+		t.Errorf("expected key=2 in main")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestS3FIFO_Evict_Main(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(1)
+	p.OnHit(2)
+	p.Evict() // both move to main, main evicts 1
+
+	p.OnSet(3)
+	p.OnSet(3)
+	p.Evict() // 3 moves to main, main evicts 2
+
+	p.OnHit(3)
+	// small empty: main reinserts 3 once, then evicts it
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 3 {
+		t.Errorf("expected k=3, got %d", k)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestS3FIFO_Evict_Empty(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestS3FIFO_ghostCap(t *testing.T) {
+	p := NewS3FIFO[int](4) // smallCap=1, ghostCap=3
+	for i := range 10 {
+		p.OnSet(i)
+		p.Evict()
+	}
+
+	if p.ghost.len != 3 {
+		t.Errorf("expected len(ghost)=3, got %d", p.ghost.len)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+// Without a capacity ghosts are bounded by the resident keys.
+func TestS3FIFO_ghostCap_NoCapacity(t *testing.T) {
+	p := NewS3FIFO[int](0)
+	for i := range 100 {
+		p.OnSet(i)
+	}
+	for i := 100; i < 10_000; i++ {
+		p.OnSet(i)
+		p.Evict()
+	}
+
+	if p.Len() != 100 {
+		t.Errorf("expected len=100, got %d", p.Len())
+	}
+	if p.ghost.len == 0 || p.ghost.len > p.Len() {
+		t.Errorf("expected 0 < len(ghost) <= 100, got %d", p.ghost.len)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestS3FIFO_OnDel(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict() // 1 -> ghost
+
+	p.OnDel(1)
+	p.OnDel(2)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if len(p.nodes) != 0 {
+		t.Errorf("expected no nodes, got %d", len(p.nodes))
+	}
+}
+
+func TestS3FIFO_Reset(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if len(p.nodes) != 0 {
+		t.Errorf("expected no nodes, got %d", len(p.nodes))
+	}
+}
+
+func TestS3FIFO_Len(t *testing.T) {
+	p := NewS3FIFO[int](10)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+	p.Evict()
+
+	if p.Len() != 2 {
+		t.Errorf("expected 2, got %d", p.Len())
+	}
+}
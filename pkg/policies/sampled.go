@@ -0,0 +1,136 @@
+package policies
+
+import (
+	"reflect"
+)
+
+// DefaultSamples is the number of keys sampled per eviction, the same
+// default Redis uses.
+const DefaultSamples = 5
+
+// defaultHalfLife is the number of shard accesses after which the frequency
+// of a key that wasn't accessed in the meantime is halved.
+const defaultHalfLife = 1 << 16
+
+// Sample is the access metadata a shard keeps for every key for sampling
+// policies. Access is the last access on the shard's logical clock.
+type Sample struct {
+	Access uint64
+	Freq   uint32
+}
+
+// Sampler is implemented by policies without per-key state. Instead of
+// asking the policy for a victim, the shard samples Samples() resident
+// entries and evicts the one that ranks first according to Less.
+type Sampler interface {
+	Samples() int
+	// Touch returns the metadata of an entry after it was accessed at now.
+	Touch(s Sample, now uint64) Sample
+	// Less reports whether a should be evicted before b.
+	Less(a, b Sample, now uint64) bool
+}
+
+// Sampled approximates LRU or LFU like Redis does: on eviction a few random
+// keys are sampled and the best candidate among them is evicted. All state
+// lives in the shard, next to the store, so the policy has nothing that can
+// get out of sync and no memory is spent per key beyond the metadata.
+// The Policy hooks are no-ops and Len always returns 0.
+type Sampled[K comparable] struct {
+	ptype    PolicyType
+	samples  int
+	halfLife uint64 // LFU only
+}
+
+// NewSampledLRU returns a policy that evicts the least recently accessed of
+// samples random keys, samples <= 0 uses DefaultSamples.
+func NewSampledLRU[K comparable](samples int) *Sampled[K] {
+	return newSampled[K](TypeSampledLRU, samples)
+}
+
+// NewSampledLFU returns a policy that evicts the least frequently accessed
+// of samples random keys, samples <= 0 uses DefaultSamples. Frequencies
+// decay while a key isn't accessed.
+func NewSampledLFU[K comparable](samples int) *Sampled[K] {
+	return newSampled[K](TypeSampledLFU, samples)
+}
+
+func newSampled[K comparable](t PolicyType, samples int) *Sampled[K] {
+	if samples <= 0 {
+		samples = DefaultSamples
+	}
+	return &Sampled[K]{
+		ptype:    t,
+		samples:  samples,
+		halfLife: defaultHalfLife,
+	}
+}
+
+func (p *Sampled[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return p.ptype, t
+}
+
+func (p *Sampled[K]) OnHit(K) {}
+
+func (p *Sampled[K]) OnSet(K) {}
+
+func (p *Sampled[K]) OnDel(K) {}
+
+// Evict never returns a key, the shard samples its entries instead.
+func (p *Sampled[K]) Evict() (K, bool) {
+	var zero K
+	return zero, false
+}
+
+func (p *Sampled[K]) Reset() {}
+
+func (p *Sampled[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+// Len returns 0, the policy doesn't track keys.
+func (p *Sampled[K]) Len() int {
+	return 0
+}
+
+// KeyOverhead returns 0, the metadata is kept and accounted for by the shard.
+func (p *Sampled[K]) KeyOverhead() int64 {
+	return 0
+}
+
+func (p *Sampled[K]) Samples() int {
+	return p.samples
+}
+
+func (p *Sampled[K]) Touch(s Sample, now uint64) Sample {
+	if p.ptype == TypeSampledLFU {
+		s.Freq = p.decay(s, now)
+	}
+	if s.Freq < ^uint32(0) {
+		s.Freq++
+	}
+	s.Access = now
+	return s
+}
+
+func (p *Sampled[K]) Less(a, b Sample, now uint64) bool {
+	if p.ptype == TypeSampledLFU {
+		fa, fb := p.decay(a, now), p.decay(b, now)
+		if fa != fb {
+			return fa < fb
+		}
+	}
+	return a.Access < b.Access
+}
+
+// decay halves the frequency for every halfLife accesses since s was last
+// accessed.
+func (p *Sampled[K]) decay(s Sample, now uint64) uint32 {
+	halvings := (now - s.Access) / p.halfLife
+	if halvings >= 32 {
+		return 0
+	}
+	return s.Freq >> halvings
+}
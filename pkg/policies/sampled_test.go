@@ -0,0 +1,98 @@
+package policies
+
+import "testing"
+
+func TestNewSampled(t *testing.T) {
+	p := NewSampledLRU[int](0)
+	if p.Samples() != DefaultSamples {
+		t.Errorf("expected samples=%d, got %d", DefaultSamples, p.Samples())
+	}
+	if ptype, _ := p.Type(); ptype != TypeSampledLRU {
+		t.Errorf("expected 'Sampled-LRU', got %v", ptype)
+	}
+	p = NewSampledLFU[int](10)
+	if p.Samples() != 10 {
+		t.Errorf("expected samples=10, got %d", p.Samples())
+	}
+	if ptype, _ := p.Type(); ptype != TypeSampledLFU {
+		t.Errorf("expected 'Sampled-LFU', got %v", ptype)
+	}
+}
+
+// hooks don't track keys
+func TestSampled_NoState(t *testing.T) {
+	p := NewSampledLRU[int](5)
+	p.OnSet(1)
+	p.OnHit(1)
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if _, ok := p.Evict(); ok {
+		t.Errorf("expected evict=false, got true")
+	}
+}
+
+func TestSampled_Touch(t *testing.T) {
+	p := NewSampledLRU[int](5)
+	s := p.Touch(Sample{}, 1)
+	s = p.Touch(s, 5)
+	if s.Access != 5 || s.Freq != 2 {
+		t.Errorf("expected {5 2}, got %+v", s)
+	}
+
+	s = p.Touch(Sample{Freq: ^uint32(0)}, 6)
+	if s.Freq != ^uint32(0) {
+		t.Errorf("expected saturated freq, got %d", s.Freq)
+	}
+}
+
+func TestSampledLRU_Less(t *testing.T) {
+	p := NewSampledLRU[int](5)
+	older := Sample{Access: 1, Freq: 100}
+	newer := Sample{Access: 2, Freq: 1}
+	if !p.Less(older, newer, 3) {
+		t.Errorf("expected older entry first")
+	}
+	if p.Less(newer, older, 3) {
+		t.Errorf("expected newer entry last")
+	}
+}
+
+func TestSampledLFU_Less(t *testing.T) {
+	p := NewSampledLFU[int](5)
+	rare := Sample{Access: 2, Freq: 1}
+	frequent := Sample{Access: 1, Freq: 10}
+	if !p.Less(rare, frequent, 3) {
+		t.Errorf("expected rare entry first")
+	}
+
+	// ties are broken by recency
+	if !p.Less(Sample{Access: 1, Freq: 1}, rare, 3) {
+		t.Errorf("expected older entry first")
+	}
+}
+
+func TestSampledLFU_Decay(t *testing.T) {
+	p := NewSampledLFU[int](5)
+	s := Sample{Access: 0, Freq: 8}
+	if f := p.decay(s, p.halfLife-1); f != 8 {
+		t.Errorf("expected 8, got %d", f)
+	}
+	if f := p.decay(s, 2*p.halfLife); f != 2 {
+		t.Errorf("expected 2, got %d", f)
+	}
+	if f := p.decay(s, 40*p.halfLife); f != 0 {
+		t.Errorf("expected 0, got %d", f)
+	}
+
+	// an old popular key loses against a recent one
+	recent := Sample{Access: 3 * p.halfLife, Freq: 2}
+	if !p.Less(s, recent, 3*p.halfLife) {
+		t.Errorf("expected decayed entry first")
+	}
+
+	// touch decays before counting the access
+	if s = p.Touch(s, 2*p.halfLife); s.Freq != 3 {
+		t.Errorf("expected freq=3, got %d", s.Freq)
+	}
+}
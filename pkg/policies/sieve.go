@@ -0,0 +1,118 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+// SIEVE keeps keys in a single insertion ordered queue with a visited bit.
+// A hit only sets the bit, so OnHit never moves nodes. On eviction a hand
+// sweeps from the oldest key towards the newest, clearing visited bits, and
+// evicts the first key that wasn't visited; the hand keeps its position for
+// the next eviction.
+type SIEVE[K comparable] struct {
+	queue *keyList[K]
+	nodes map[K]*keyNode[K]
+	hand  *keyNode[K]
+}
+
+func NewSIEVE[K comparable]() *SIEVE[K] {
+	return &SIEVE[K]{
+		queue: &keyList[K]{},
+		nodes: make(map[K]*keyNode[K]),
+	}
+}
+
+func (p *SIEVE[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeSIEVE, t
+}
+
+// fails silently when key is not in policy
+func (p *SIEVE[K]) OnHit(k K) {
+	if n := p.nodes[k]; n != nil {
+		n.freq = 1
+	}
+}
+
+func (p *SIEVE[K]) OnSet(k K) {
+	if _, ok := p.nodes[k]; ok {
+		p.OnHit(k)
+		return
+	}
+	n := &keyNode[K]{key: k}
+	p.nodes[k] = n
+	p.queue.pushFront(n)
+}
+
+func (p *SIEVE[K]) OnDel(k K) {
+	if n := p.nodes[k]; n != nil {
+		p.remove(n)
+	}
+}
+
+func (p *SIEVE[K]) Evict() (K, bool) {
+	if p.queue.len == 0 {
+		var zero K
+		return zero, false
+	}
+	n := p.hand
+	if n == nil {
+		n = p.queue.back()
+	}
+	for n.freq > 0 {
+		n.freq = 0
+		n = n.prev
+		if n == nil {
+			n = p.queue.back()
+		}
+	}
+	p.hand = n
+	p.remove(n)
+	return n.key, true
+}
+
+func (p *SIEVE[K]) Reset() {
+	p.queue.reset()
+	clear(p.nodes)
+	p.hand = nil
+}
+
+func (p *SIEVE[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+func (p *SIEVE[K]) Len() int {
+	return p.queue.len
+}
+
+// KeyOverhead estimates the bytes spent per key: its node and map entry.
+func (p *SIEVE[K]) KeyOverhead() int64 {
+	return sizer.Static[keyNode[K]]() + sizer.MapEntry[K, *keyNode[K]]()
+}
+
+// remove unlinks n, moving the hand on to the next newer node if needed.
+func (p *SIEVE[K]) remove(n *keyNode[K]) {
+	if p.hand == n {
+		p.hand = n.prev
+	}
+	p.queue.remove(n)
+	delete(p.nodes, n.key)
+}
+
+func (p *SIEVE[K]) validate() error {
+	if err := p.queue.validate(p.nodes); err != nil {
+		return err
+	}
+	if p.queue.len != len(p.nodes) {
+		return fmt.Errorf("len(map): %d != len(queue): %d", len(p.nodes), p.queue.len)
+	}
+	if p.hand != nil && p.hand.list != p.queue {
+		return fmt.Errorf("hand '%v' not in queue", p.hand.key)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package policies
+
+import "testing"
+
+func TestNewSIEVE(t *testing.T) {
+	p := NewSIEVE[int]()
+	if p.nodes == nil {
+		t.Fatalf("expected nodes to be set, got nil")
+	}
+	if p.hand != nil {
+		t.Errorf("expected hand=nil, got %v", p.hand)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+}
+
+func TestSIEVE_Type(t *testing.T) {
+	p := NewSIEVE[int]()
+	ptype, ktype := p.Type()
+	if ptype != TypeSIEVE {
+		t.Errorf("expected 'SIEVE', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestSIEVE_OnSet(t *testing.T) {
+	p := NewSIEVE[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(1) // duplicate marks visited
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 2 {
+		t.Fatalf("expected len=2, got %d", p.Len())
+	}
+	if p.queue.head.key != 2 {
+		t.Errorf("expected key=2 in front, got %d", p.queue.head.key)
+	}
+	if p.nodes[1].freq != 1 {
+		t.Errorf("expected key=1 visited")
+	}
+}
+
+func TestSIEVE_OnHit(t *testing.T) {
+	p := NewSIEVE[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+
+	p.OnHit(1)
+	// hits don't move nodes
+	if p.queue.back().key != 1 {
+		t.Errorf("expected key=1 at the back, got %d", p.queue.back().key)
+	}
+	if p.nodes[1].freq != 1 {
+		t.Errorf("expected key=1 visited")
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(3)
+	if p.Len() != 2 {
+		t.Errorf("expected len=2, got %d", p.Len())
+	}
+}
+
+func TestSIEVE_Evict(t *testing.T) {
+	p := NewSIEVE[int]()
+	for i := 1; i <= 4; i++ {
+		p.OnSet(i)
+	}
+	p.OnHit(1)
+	p.OnHit(3)
+
+	// 1 is visited and spared, 2 is evicted
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 2 {
+		t.Errorf("expected k=2, got %d", k)
+	}
+	if p.nodes[1].freq != 0 {
+		t.Errorf("expected key=1 visited bit cleared")
+	}
+	// hand continues at 3: visited, so 4 goes
+	k, _ = p.Evict()
+	if k != 4 {
+		t.Errorf("expected k=4, got %d", k)
+	}
+	// hand wraps around to the back
+	k, _ = p.Evict()
+	if k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestSIEVE_Evict_Empty(t *testing.T) {
+	p := NewSIEVE[int]()
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+func TestSIEVE_OnDel(t *testing.T) {
+	p := NewSIEVE[int]()
+	for i := 1; i <= 3; i++ {
+		p.OnSet(i)
+	}
+	p.Evict() // evicts 1, hand on 2
+
+	p.OnDel(2)
+	if p.hand == nil || p.hand.key != 3 {
+		t.Errorf("expected hand on key=3, got %v", p.hand)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+
+	p.OnDel(4) // not in policy
+	if p.Len() != 1 {
+		t.Errorf("expected len=1, got %d", p.Len())
+	}
+}
+
+func TestSIEVE_Reset(t *testing.T) {
+	p := NewSIEVE[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.Evict()
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if p.hand != nil {
+		t.Errorf("expected hand=nil, got %v", p.hand)
+	}
+}
+
+func TestSIEVE_Len(t *testing.T) {
+	p := NewSIEVE[int]()
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if p.Len() != 3 {
+		t.Errorf("expected 3, got %d", p.Len())
+	}
+}
@@ -0,0 +1,149 @@
+package policies
+
+import "math/bits"
+
+const (
+	sketchDepth   = 4
+	counterMax    = 15
+	resetMask     = 0x7777777777777777
+	doorkeeperK   = 2
+	sampleFactor  = 10
+	minSketchSize = 64
+)
+
+// frequencySketch estimates how often a key was seen recently.
+// It is a count-min sketch of 4-bit counters (16 per word) fronted by a bloom
+// filter doorkeeper: the first occurrence of a key only sets doorkeeper bits,
+// so one-hit wonders never reach the counters. After sampleSize increments
+// all counters are halved and the doorkeeper is cleared, which keeps the
+// estimates biased towards recent history.
+type frequencySketch struct {
+	table      []uint64
+	doorkeeper []uint64
+	additions  int
+	sampleSize int
+}
+
+// newFrequencySketch sizes the sketch for roughly size distinct keys.
+func newFrequencySketch(size int) *frequencySketch {
+	size = max(size, minSketchSize)
+	n := nextPow2(size)
+	return &frequencySketch{
+		table:      make([]uint64, n/4), // 4 counters per key
+		doorkeeper: make([]uint64, n/8), // 8 bits per key
+		sampleSize: sampleFactor * size,
+	}
+}
+
+// increment records an occurrence of hash.
+func (f *frequencySketch) increment(hash uint64) {
+	if !f.doorkeeperAdd(hash) {
+		return
+	}
+	added := false
+	for i := range sketchDepth {
+		added = f.incrementAt(f.index(hash, i)) || added
+	}
+	if added {
+		f.additions++
+		if f.additions >= f.sampleSize {
+			f.reset()
+		}
+	}
+}
+
+// estimate returns the approximate number of occurrences of hash.
+func (f *frequencySketch) estimate(hash uint64) int {
+	freq := counterMax
+	for i := range sketchDepth {
+		freq = min(freq, f.counterAt(f.index(hash, i)))
+	}
+	if f.doorkeeperContains(hash) {
+		freq++
+	}
+	return freq
+}
+
+// reset halves all counters and clears the doorkeeper.
+func (f *frequencySketch) reset() {
+	for i, w := range f.table {
+		f.table[i] = (w >> 1) & resetMask
+	}
+	clear(f.doorkeeper)
+	f.additions /= 2
+}
+
+func (f *frequencySketch) clearAll() {
+	clear(f.table)
+	clear(f.doorkeeper)
+	f.additions = 0
+}
+
+// index returns the counter used for hash in row i (double hashing).
+func (f *frequencySketch) index(hash uint64, i int) int {
+	h := uint32(hash) + uint32(i)*uint32(hash>>32)
+	return int(h) & (len(f.table)*16 - 1)
+}
+
+func (f *frequencySketch) counterAt(idx int) int {
+	shift := (idx & 15) * 4
+	return int((f.table[idx>>4] >> shift) & 0xf)
+}
+
+func (f *frequencySketch) incrementAt(idx int) bool {
+	shift := (idx & 15) * 4
+	w := &f.table[idx>>4]
+	if (*w>>shift)&0xf == counterMax {
+		return false
+	}
+	*w += 1 << shift
+	return true
+}
+
+// doorkeeperAdd sets the bits for hash and reports whether they were all set
+// already.
+func (f *frequencySketch) doorkeeperAdd(hash uint64) bool {
+	seen := true
+	for i := range doorkeeperK {
+		idx := f.bit(hash, i)
+		mask := uint64(1) << (idx & 63)
+		if f.doorkeeper[idx>>6]&mask == 0 {
+			seen = false
+			f.doorkeeper[idx>>6] |= mask
+		}
+	}
+	return seen
+}
+
+func (f *frequencySketch) doorkeeperContains(hash uint64) bool {
+	for i := range doorkeeperK {
+		idx := f.bit(hash, i)
+		if f.doorkeeper[idx>>6]&(uint64(1)<<(idx&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *frequencySketch) bit(hash uint64, i int) int {
+	h := uint32(hash>>32) + uint32(i+sketchDepth)*uint32(hash)
+	return int(h) & (len(f.doorkeeper)*64 - 1)
+}
+
+// spread mixes the bits of a hash (splitmix64 finalizer). Shards select keys
+// on the low bits of the same hash, so these must be remixed first.
+func spread(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
@@ -0,0 +1,113 @@
+package policies
+
+import "testing"
+
+func TestNewFrequencySketch(t *testing.T) {
+	f := newFrequencySketch(100)
+	if l := len(f.table); l != 32 {
+		t.Errorf("expected len(table)=32, got %d", l)
+	}
+	if l := len(f.doorkeeper); l != 16 {
+		t.Errorf("expected len(doorkeeper)=16, got %d", l)
+	}
+	if f.sampleSize != 1000 {
+		t.Errorf("expected sampleSize=1000, got %d", f.sampleSize)
+	}
+
+	// small sizes are clamped
+	f = newFrequencySketch(0)
+	if l := len(f.table); l != 16 {
+		t.Errorf("expected len(table)=16, got %d", l)
+	}
+}
+
+func TestFrequencySketch_increment(t *testing.T) {
+	f := newFrequencySketch(100)
+	h := spread(42)
+
+	if e := f.estimate(h); e != 0 {
+		t.Fatalf("expected 0, got %d", e)
+	}
+	// first occurrence only hits the doorkeeper
+	f.increment(h)
+	if e := f.estimate(h); e != 1 {
+		t.Fatalf("expected 1, got %d", e)
+	}
+	if f.additions != 0 {
+		t.Errorf("expected additions=0, got %d", f.additions)
+	}
+	for range 4 {
+		f.increment(h)
+	}
+	if e := f.estimate(h); e != 5 {
+		t.Errorf("expected 5, got %d", e)
+	}
+
+	// counters saturate
+	for range 100 {
+		f.increment(h)
+	}
+	if e := f.estimate(h); e != counterMax+1 {
+		t.Errorf("expected %d, got %d", counterMax+1, e)
+	}
+}
+
+func TestFrequencySketch_reset(t *testing.T) {
+	f := newFrequencySketch(100)
+	h := spread(42)
+	for range 9 {
+		f.increment(h) // doorkeeper + 8
+	}
+
+	f.reset()
+
+	if e := f.estimate(h); e != 4 {
+		t.Errorf("expected 4, got %d", e)
+	}
+	if f.doorkeeperContains(h) {
+		t.Errorf("expected doorkeeper to be cleared")
+	}
+}
+
+func TestFrequencySketch_reset_SampleSize(t *testing.T) {
+	f := newFrequencySketch(64)
+	f.sampleSize = 10
+	h := spread(1)
+	for range 11 {
+		f.increment(h)
+	}
+	// 10 additions triggered a reset
+	if f.additions != 5 {
+		t.Errorf("expected additions=5, got %d", f.additions)
+	}
+	if e := f.estimate(h); e != 5 {
+		t.Errorf("expected 5, got %d", e)
+	}
+}
+
+func TestFrequencySketch_clearAll(t *testing.T) {
+	f := newFrequencySketch(64)
+	h := spread(1)
+	f.increment(h)
+	f.increment(h)
+
+	f.clearAll()
+
+	if e := f.estimate(h); e != 0 {
+		t.Errorf("expected 0, got %d", e)
+	}
+	if f.additions != 0 {
+		t.Errorf("expected additions=0, got %d", f.additions)
+	}
+}
+
+func TestNextPow2(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{0, 1}, {1, 1}, {2, 2}, {3, 4}, {64, 64}, {65, 128},
+	}
+	for _, tt := range tests {
+		if got := nextPow2(tt.in); got != tt.want {
+			t.Errorf("nextPow2(%d): expected %d, got %d", tt.in, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jeltjongsma/go-cache/pkg/hasher"
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+const (
+	windowPercent    = 1
+	protectedPercent = 80
+)
+
+// WTinyLFU combines a small LRU admission window with a segmented LRU main
+// area (probation + protected), guarded by a TinyLFU frequency filter.
+// New keys always enter the window, there is no admission check up front.
+// When the window is full its oldest key competes with the main area's victim
+// and only the one seen more often stays, so a burst of one-hit wonders can't
+// flush out hot keys. Keys hit while on
+// probation are promoted to the protected segment.
+type WTinyLFU[K comparable] struct {
+	hasher                       *hasher.Hasher[K]
+	sketch                       *frequencySketch
+	window, probation, protected *keyList[K]
+	nodes                        map[K]*keyNode[K]
+	windowCap, protectedCap      int
+}
+
+// NewWTinyLFU returns a W-TinyLFU policy for a shard that holds at most cap
+// keys. h is used to hash keys into the frequency sketch, a nil h uses the
+// default hasher.
+func NewWTinyLFU[K comparable](cap int, h *hasher.Hasher[K]) *WTinyLFU[K] {
+	if h == nil {
+		h = hasher.NewHasher[K](nil)
+	}
+	cap = max(cap, 0)
+	windowCap := max(1, cap*windowPercent/100)
+	return &WTinyLFU[K]{
+		hasher:       h,
+		sketch:       newFrequencySketch(cap),
+		window:       &keyList[K]{},
+		probation:    &keyList[K]{},
+		protected:    &keyList[K]{},
+		nodes:        make(map[K]*keyNode[K]),
+		windowCap:    windowCap,
+		protectedCap: max(0, cap-windowCap) * protectedPercent / 100,
+	}
+}
+
+func (p *WTinyLFU[K]) Type() (PolicyType, reflect.Type) {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return TypeWTinyLFU, t
+}
+
+// fails silently when key is not in policy
+func (p *WTinyLFU[K]) OnHit(k K) {
+	n := p.nodes[k]
+	if n == nil {
+		return
+	}
+	p.record(k)
+
+	switch n.list {
+	case p.window, p.protected:
+		n.list.moveToFront(n)
+	case p.probation:
+		p.probation.remove(n)
+		p.protected.pushFront(n)
+		for p.protected.len > p.protectedCap {
+			demoted := p.protected.back()
+			p.protected.remove(demoted)
+			p.probation.pushFront(demoted)
+		}
+	}
+}
+
+func (p *WTinyLFU[K]) OnSet(k K) {
+	if _, ok := p.nodes[k]; ok {
+		p.OnHit(k)
+		return
+	}
+	p.record(k)
+
+	n := &keyNode[K]{key: k}
+	p.nodes[k] = n
+	p.window.pushFront(n)
+	// without pressure the window simply spills into probation
+	for p.window.len > p.windowCap {
+		spilled := p.window.back()
+		p.window.remove(spilled)
+		p.probation.pushFront(spilled)
+	}
+}
+
+func (p *WTinyLFU[K]) OnDel(k K) {
+	if n := p.nodes[k]; n != nil {
+		n.list.remove(n)
+		delete(p.nodes, k)
+	}
+}
+
+// OnMiss counts a miss as an access, so keys that are requested often but
+// keep getting evicted build up frequency.
+func (p *WTinyLFU[K]) OnMiss(k K) {
+	p.record(k)
+}
+
+func (p *WTinyLFU[K]) Evict() (K, bool) {
+	victim, candidate := p.victim()
+	if victim == nil {
+		var zero K
+		return zero, false
+	}
+	if candidate != nil {
+		// window candidate won the admission contest
+		p.window.remove(candidate)
+		p.probation.pushFront(candidate)
+	}
+	victim.list.remove(victim)
+	delete(p.nodes, victim.key)
+	return victim.key, true
+}
+
+func (p *WTinyLFU[K]) Reset() {
+	p.window.reset()
+	p.probation.reset()
+	p.protected.reset()
+	clear(p.nodes)
+	p.sketch.clearAll()
+}
+
+func (p *WTinyLFU[K]) Equals(o Policy[any]) bool {
+	pPtype, pKtype := p.Type()
+	oPtype, oKtype := o.Type()
+	return pPtype == oPtype && pKtype == oKtype
+}
+
+func (p *WTinyLFU[K]) Len() int {
+	return len(p.nodes)
+}
+
+// KeyOverhead estimates the bytes spent per key: its node, map entry and
+// share of the sketch.
+func (p *WTinyLFU[K]) KeyOverhead() int64 {
+	return sizer.Static[keyNode[K]]() + sizer.MapEntry[K, *keyNode[K]]() + 8
+}
+
+// victim returns the node Evict would remove to make room for a new key in
+// the window. When the window is full the new key pushes out its oldest key,
+// which competes with the oldest key of the main area; if the window key wins
+// it is returned as candidate and should move to probation.
+func (p *WTinyLFU[K]) victim() (victim, candidate *keyNode[K]) {
+	main := p.probation.back()
+	if main == nil {
+		main = p.protected.back()
+	}
+	cand := p.window.back()
+
+	switch {
+	case cand == nil:
+		return main, nil
+	case main == nil:
+		return cand, nil
+	case p.window.len < p.windowCap:
+		return main, nil
+	case p.frequency(cand.key) > p.frequency(main.key):
+		return main, cand
+	default:
+		return cand, nil
+	}
+}
+
+func (p *WTinyLFU[K]) record(k K) {
+	p.sketch.increment(spread(p.hasher.Hash(k)))
+}
+
+func (p *WTinyLFU[K]) frequency(k K) int {
+	return p.sketch.estimate(spread(p.hasher.Hash(k)))
+}
+
+func (p *WTinyLFU[K]) validate() error {
+	total := 0
+	for _, l := range []*keyList[K]{p.window, p.probation, p.protected} {
+		if err := l.validate(p.nodes); err != nil {
+			return err
+		}
+		total += l.len
+	}
+	if total != len(p.nodes) {
+		return fmt.Errorf("len(map): %d != len(lists): %d", len(p.nodes), total)
+	}
+	if p.window.len > p.windowCap {
+		return fmt.Errorf("window len %d > cap %d", p.window.len, p.windowCap)
+	}
+	if p.protected.len > p.protectedCap {
+		return fmt.Errorf("protected len %d > cap %d", p.protected.len, p.protectedCap)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package policies
+
+import "testing"
+
+func TestNewWTinyLFU(t *testing.T) {
+	p := NewWTinyLFU[int](1000, nil)
+	if p.hasher == nil {
+		t.Fatalf("expected hasher to be set, got nil")
+	}
+	if p.windowCap != 10 {
+		t.Errorf("expected windowCap=10, got %d", p.windowCap)
+	}
+	if p.protectedCap != 792 {
+		t.Errorf("expected protectedCap=792, got %d", p.protectedCap)
+	}
+
+	p = NewWTinyLFU[int](10, nil)
+	if p.windowCap != 1 {
+		t.Errorf("expected windowCap=1, got %d", p.windowCap)
+	}
+}
+
+func TestWTinyLFU_Type(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil)
+	ptype, ktype := p.Type()
+	if ptype != TypeWTinyLFU {
+		t.Errorf("expected 'W-TinyLFU', got %v", ptype)
+	}
+	if ktype.String() != "int" {
+		t.Errorf("expected 'int', got %s", ktype.String())
+	}
+}
+
+func TestWTinyLFU_OnSet(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if n := p.nodes[3]; n.list != p.window {
+		t.Errorf("expected key=3 in window")
+	}
+	// older keys spill into probation
+	if l := p.probation.len; l != 2 {
+		t.Errorf("expected len(probation)=2, got %d", l)
+	}
+	if p.Len() != 3 {
+		t.Errorf("expected len=3, got %d", p.Len())
+	}
+}
+
+func TestWTinyLFU_OnHit(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil) // protectedCap=7
+	for i := range 9 {
+		p.OnSet(i)
+	}
+
+	p.OnHit(0)
+	if n := p.nodes[0]; n.list != p.protected {
+		t.Errorf("expected key=0 in protected")
+	}
+
+	// overflowing protected demotes its oldest key
+	for i := 1; i < 8; i++ {
+		p.OnHit(i)
+	}
+	if n := p.nodes[0]; n.list != p.probation {
+		t.Errorf("expected key=0 demoted to probation")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+
+	// key not in policy shouldn't affect policy
+	p.OnHit(100)
+	if p.Len() != 9 {
+		t.Errorf("expected len=9, got %d", p.Len())
+	}
+}
+
+func TestWTinyLFU_OnDel(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(1)
+
+	p.OnDel(1)
+	p.OnDel(2)
+	p.OnDel(3)
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+}
+
+func TestWTinyLFU_Evict(t *testing.T) {
+	p := NewWTinyLFU[int](3, nil) // windowCap=1
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3) // window: 3, probation: 2, 1
+
+	// cold window candidate loses against equally cold main victim
+	k, ok := p.Evict()
+	if !ok {
+		t.Fatalf("expected ok=true, got false")
+	}
+	if k != 3 {
+		t.Errorf("expected k=3, got %d", k)
+	}
+
+	p.OnSet(4)
+	for range 3 {
+		p.OnMiss(4) // 4 is popular
+	}
+	// window candidate wins and moves to probation, main victim goes
+	k, _ = p.Evict()
+	if k != 1 {
+		t.Errorf("expected k=1, got %d", k)
+	}
+	if n := p.nodes[4]; n.list != p.probation {
+		t.Errorf("expected key=4 in probation")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestWTinyLFU_Evict_Empty(t *testing.T) {
+	p := NewWTinyLFU[int](3, nil)
+	_, ok := p.Evict()
+	if ok {
+		t.Errorf("expected ok=false, got true")
+	}
+}
+
+// A full policy takes new keys into the window, the key pushed out of the
+// window competes with the probation victim.
+func TestWTinyLFU_NewKeysEnterWindow(t *testing.T) {
+	p := NewWTinyLFU[int](2, nil) // windowCap=1
+	p.OnSet(1)
+	p.OnSet(2)
+	for range 5 {
+		p.OnHit(1)
+	}
+
+	// 2 was pushed to probation, 1 is in the window and seen more often
+	if k, ok := p.Evict(); !ok || k != 2 {
+		t.Fatalf("expected (2, true), got (%d, %v)", k, ok)
+	}
+	p.OnSet(3)
+	if n := p.nodes[3]; n == nil || n.list != p.window {
+		t.Fatalf("expected key=3 in window")
+	}
+	if n := p.nodes[1]; n == nil || n.list != p.probation {
+		t.Fatalf("expected key=1 in probation")
+	}
+
+	// 3 is pushed out of the window by 4 and loses against 1
+	if k, ok := p.Evict(); !ok || k != 3 {
+		t.Fatalf("expected (3, true), got (%d, %v)", k, ok)
+	}
+	p.OnSet(4)
+	if n := p.nodes[4]; n == nil || n.list != p.window {
+		t.Errorf("expected key=4 in window")
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestWTinyLFU_ScanResistance(t *testing.T) {
+	const cap = 100
+	p := NewWTinyLFU[int](cap, nil)
+	// a miss followed by a set, like a read-through cache does
+	set := func(k int) {
+		p.OnMiss(k)
+		if p.Len() >= cap {
+			p.Evict()
+		}
+		p.OnSet(k)
+	}
+
+	for i := range cap {
+		set(i)
+	}
+	for range 5 {
+		for i := range cap {
+			p.OnHit(i)
+		}
+	}
+	// scan of one-hit wonders, long enough to flush an LRU between
+	// two rounds of hot key accesses
+	for i := cap; i < 100*cap; i++ {
+		set(i)
+		if i%(2*cap) == 0 {
+			for k := range cap {
+				p.OnHit(k)
+			}
+		}
+	}
+
+	hot := 0
+	for i := range cap {
+		if _, ok := p.nodes[i]; ok {
+			hot++
+		}
+	}
+	if hot < cap*3/4 {
+		t.Errorf("expected at least %d hot keys to survive, got %d", cap*3/4, hot)
+	}
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+}
+
+func TestWTinyLFU_Reset(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnHit(1)
+
+	p.Reset()
+
+	if err := p.validate(); err != nil {
+		t.Fatalf("policy not valid: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("expected len=0, got %d", p.Len())
+	}
+	if f := p.frequency(1); f != 0 {
+		t.Errorf("expected frequency=0, got %d", f)
+	}
+}
+
+func TestWTinyLFU_Len(t *testing.T) {
+	p := NewWTinyLFU[int](10, nil)
+	p.OnSet(1)
+	p.OnSet(2)
+	p.OnSet(3)
+
+	if p.Len() != 3 {
+		t.Errorf("expected 3, got %d", p.Len())
+	}
+}
@@ -0,0 +1,117 @@
+// Package sizer estimates the memory footprint of keys and values.
+package sizer
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// MapOverhead approximates the bytes a map spends per entry on top of its
+// key and value: control bytes and empty slots at an average load factor.
+const MapOverhead = 16
+
+// maxDepth bounds how far Of follows references, which also stops it on
+// cyclic data.
+const maxDepth = 8
+
+// Static returns the size of T itself, without the memory it references.
+func Static[T any]() int64 {
+	var v T
+	return int64(unsafe.Sizeof(v))
+}
+
+// MapEntry returns the approximate bytes an entry of a map[K]V takes.
+func MapEntry[K comparable, V any]() int64 {
+	return Static[K]() + Static[V]() + MapOverhead
+}
+
+// Of estimates the bytes held by v: its static size plus the memory it
+// references (string data, backing arrays, maps and pointees). Memory shared
+// between references is counted for each of them.
+func Of[T any](v T) int64 {
+	// switching on a pointer only matches when T itself is the type
+	switch p := any(&v).(type) {
+	case *string:
+		return Static[string]() + int64(len(*p))
+	case *[]byte:
+		return Static[[]byte]() + int64(cap(*p))
+	case *bool, *int, *int8, *int16, *int32, *int64,
+		*uint, *uint8, *uint16, *uint32, *uint64, *uintptr,
+		*float32, *float64, *complex64, *complex128:
+		return Static[T]()
+	}
+	return Static[T]() + indirect(reflect.ValueOf(&v).Elem(), 0)
+}
+
+// indirect returns the bytes referenced by v, not counting v itself.
+func indirect(v reflect.Value, depth int) int64 {
+	if depth > maxDepth {
+		return 0
+	}
+	var n int64
+	switch v.Kind() {
+	case reflect.String:
+		n = int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		n = int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if hasIndirect(v.Type().Elem()) {
+			for i := range v.Len() {
+				n += indirect(v.Index(i), depth+1)
+			}
+		}
+	case reflect.Array:
+		if hasIndirect(v.Type().Elem()) {
+			for i := range v.Len() {
+				n += indirect(v.Index(i), depth+1)
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return 0
+		}
+		t := v.Type()
+		n = int64(v.Len()) * (int64(t.Key().Size()) + int64(t.Elem().Size()) + MapOverhead)
+		if hasIndirect(t.Key()) || hasIndirect(t.Elem()) {
+			it := v.MapRange()
+			for it.Next() {
+				n += indirect(it.Key(), depth+1) + indirect(it.Value(), depth+1)
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0
+		}
+		n = int64(v.Type().Elem().Size()) + indirect(v.Elem(), depth+1)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		n = int64(e.Type().Size()) + indirect(e, depth+1)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			n += indirect(v.Field(i), depth+1)
+		}
+	}
+	return n
+}
+
+// hasIndirect reports whether values of t may reference other memory.
+func hasIndirect(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Pointer, reflect.Interface:
+		return true
+	case reflect.Array:
+		return hasIndirect(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if hasIndirect(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
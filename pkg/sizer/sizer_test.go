@@ -0,0 +1,72 @@
+package sizer
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestStatic(t *testing.T) {
+	if s := Static[int64](); s != 8 {
+		t.Errorf("expected 8, got %d", s)
+	}
+	if s := Static[string](); s != int64(unsafe.Sizeof("")) {
+		t.Errorf("expected %d, got %d", unsafe.Sizeof(""), s)
+	}
+}
+
+func TestMapEntry(t *testing.T) {
+	if s := MapEntry[int64, int32](); s != 12+MapOverhead {
+		t.Errorf("expected %d, got %d", 12+MapOverhead, s)
+	}
+}
+
+func TestOf(t *testing.T) {
+	type inner struct {
+		name string
+	}
+	type outer struct {
+		id    int64
+		tags  []string
+		inner *inner
+	}
+
+	sliceHdr := Static[[]string]()
+	strHdr := Static[string]()
+
+	tests := []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{"int", Of(42), 8},
+		{"string", Of("hello"), strHdr + 5},
+		{"bytes", Of(make([]byte, 3, 10)), sliceHdr + 10},
+		{"nil slice", Of([]string(nil)), sliceHdr},
+		{"strings", Of([]string{"ab", "c"}), sliceHdr + 2*strHdr + 3},
+		{"map", Of(map[int64]int64{1: 1}), 8 + 16 + MapOverhead},
+		{"pointer", Of(&inner{"abc"}), 8 + strHdr + 3},
+		{"struct", Of(outer{1, []string{"a"}, &inner{"bc"}}),
+			Static[outer]() + strHdr + 1 + strHdr + 2},
+		{"interface", Of[any]("abc"), Static[any]() + strHdr + 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestOf_Cycle(t *testing.T) {
+	type node struct {
+		next *node
+	}
+	n := &node{}
+	n.next = n
+
+	// terminates
+	if s := Of(n); s <= 0 {
+		t.Errorf("expected size > 0, got %d", s)
+	}
+}
@@ -0,0 +1,49 @@
+package ttl_queue
+
+import "time"
+
+// ExpiryIndex tracks when keys expire, so expired keys can be removed
+// without scanning the store. Deadlines are absolute.
+type ExpiryIndex[K comparable] interface {
+	// Schedule lets k expire at the given time, moving it if it is already
+	// indexed.
+	Schedule(k K, at time.Time)
+	// Reschedule moves k to the given time, returns false if k isn't
+	// indexed.
+	Reschedule(k K, at time.Time) bool
+	// Cancel removes k, returns false if k isn't indexed.
+	Cancel(k K) bool
+	// PopExpired removes up to limit keys that expired at now and appends
+	// them to dst, limit <= 0 pops all of them.
+	PopExpired(dst []K, now time.Time, limit int) []K
+	// NextDeadline returns the earliest time at which PopExpired may return
+	// a key. Indexes that bucket deadlines may return a time at which nothing
+	// is due yet.
+	NextDeadline() (time.Time, bool)
+	Len() int
+	Reset()
+	// KeyOverhead estimates the bytes spent per indexed key.
+	KeyOverhead() int64
+}
+
+type IndexType string
+
+const (
+	TypeHeap        IndexType = "heap"
+	TypeTimingWheel IndexType = "timing-wheel"
+	TypeNone        IndexType = "none" // lazy expiry only, see NoIndex
+)
+
+// NewIndex returns a new index of type t.
+func NewIndex[K comparable](t IndexType) (ExpiryIndex[K], bool) {
+	switch t {
+	case TypeHeap:
+		return NewTTLQueue[K](0), true
+	case TypeTimingWheel:
+		return NewTimingWheel[K](DefaultTick), true
+	case TypeNone:
+		return NoIndex[K]{}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,37 @@
+package ttl_queue
+
+import "time"
+
+// NoIndex doesn't track expiries at all. Expired entries are only noticed
+// when they are read, and otherwise stay until they are evicted, so it only
+// suits caches that rarely use TTLs or are bounded by capacity anyway. In
+// exchange Set doesn't pay for indexing the key.
+type NoIndex[K comparable] struct{}
+
+func (NoIndex[K]) Schedule(K, time.Time) {}
+
+func (NoIndex[K]) Reschedule(K, time.Time) bool {
+	return false
+}
+
+func (NoIndex[K]) Cancel(K) bool {
+	return false
+}
+
+func (NoIndex[K]) PopExpired(dst []K, _ time.Time, _ int) []K {
+	return dst
+}
+
+func (NoIndex[K]) NextDeadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (NoIndex[K]) Len() int {
+	return 0
+}
+
+func (NoIndex[K]) Reset() {}
+
+func (NoIndex[K]) KeyOverhead() int64 {
+	return 0
+}
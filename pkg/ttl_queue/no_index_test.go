@@ -0,0 +1,27 @@
+package ttl_queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNoIndex(t *testing.T) {
+	idx, ok := NewIndex[int](TypeNone)
+	if !ok {
+		t.Fatalf("expected index for %s", TypeNone)
+	}
+	n := time.Now()
+	idx.Schedule(1, n.Add(-time.Second))
+	if idx.Reschedule(1, n) || idx.Cancel(1) {
+		t.Errorf("expected keys not to be indexed")
+	}
+	if got := idx.PopExpired(nil, n, 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+	if _, ok := idx.NextDeadline(); ok {
+		t.Errorf("expected no deadline")
+	}
+	if idx.Len() != 0 || idx.KeyOverhead() != 0 {
+		t.Errorf("expected len=0 and no overhead")
+	}
+}
@@ -0,0 +1,309 @@
+package ttl_queue
+
+import (
+	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
+)
+
+// DefaultTick is the resolution of a TimingWheel created with a tick <= 0.
+const DefaultTick = 10 * time.Millisecond
+
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits // slots per level
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+	wheelSpan   = 1 << (wheelBits * wheelLevels) // ticks covered by all levels
+	readyList   = wheelLevels * wheelSlots       // list of expired keys
+	nilNode     = -1
+)
+
+type wheelNode[K comparable] struct {
+	k          K
+	at         int64 // tick the key expires at
+	list       int32 // slot the node is linked in
+	prev, next int32 // free nodes are linked by next
+}
+
+// TimingWheel is a hierarchical timing wheel: 4 levels of 64 slots, where a
+// slot on level l holds the keys expiring in a span of 64^l ticks. When the
+// wheel turns past a slot of a higher level its keys cascade down, until they
+// reach level 0 and expire. Scheduling and cancelling are O(1).
+//
+// Deadlines are rounded up to the tick, so keys expire up to one tick late
+// but never early. Deadlines further out than 64^4 ticks are parked in the
+// last level until they come into range.
+//
+// Nodes live in a slice and are linked by index, so the wheel doesn't
+// allocate per key and holds no pointers the GC has to chase.
+type TimingWheel[K comparable] struct {
+	tick   int64 // nanoseconds
+	cur    int64 // every tick up to cur was processed
+	heads  [readyList + 1]int32
+	counts [wheelLevels]int
+	ready  int
+	nodes  []wheelNode[K]
+	free   int32
+	keys   map[K]int32
+	now    func() time.Time
+}
+
+// NewTimingWheel returns an empty wheel with the given resolution, tick <= 0
+// uses DefaultTick.
+func NewTimingWheel[K comparable](tick time.Duration) *TimingWheel[K] {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+	w := &TimingWheel[K]{
+		tick: int64(tick),
+		keys: make(map[K]int32),
+		now:  time.Now,
+	}
+	w.Reset()
+	return w
+}
+
+func (w *TimingWheel[K]) Len() int {
+	return len(w.keys)
+}
+
+// Schedule lets k expire at the given time, moving it if it is already
+// scheduled.
+func (w *TimingWheel[K]) Schedule(k K, at time.Time) {
+	if w.Reschedule(k, at) {
+		return
+	}
+	i := w.alloc()
+	w.nodes[i].k = k
+	w.nodes[i].at = w.tickOf(at)
+	w.keys[k] = i
+	w.place(i)
+}
+
+// Reschedule moves k to the given time, returns false if k isn't scheduled.
+func (w *TimingWheel[K]) Reschedule(k K, at time.Time) bool {
+	i, ok := w.keys[k]
+	if !ok {
+		return false
+	}
+	w.unlink(i)
+	w.nodes[i].at = w.tickOf(at)
+	w.place(i)
+	return true
+}
+
+// Cancel removes k, returns false if k isn't scheduled.
+func (w *TimingWheel[K]) Cancel(k K) bool {
+	i, ok := w.keys[k]
+	if !ok {
+		return false
+	}
+	w.unlink(i)
+	w.release(i)
+	return true
+}
+
+// PopExpired turns the wheel to now and appends up to limit expired keys to
+// dst, limit <= 0 pops all of them. Unlike the heap, keys aren't popped in
+// order of expiry.
+func (w *TimingWheel[K]) PopExpired(dst []K, now time.Time, limit int) []K {
+	w.advance(floorDiv(now.UnixNano(), w.tick))
+	for n := 0; limit <= 0 || n < limit; n++ {
+		i := w.heads[readyList]
+		if i == nilNode {
+			break
+		}
+		dst = append(dst, w.nodes[i].k)
+		w.unlink(i)
+		w.release(i)
+	}
+	return dst
+}
+
+// NextDeadline returns the start of the first tick at which keys expire or
+// cascade, which may be earlier than the deadline of any key.
+func (w *TimingWheel[K]) NextDeadline() (time.Time, bool) {
+	if len(w.keys) == 0 {
+		return time.Time{}, false
+	}
+	if w.ready > 0 {
+		return w.timeOf(w.cur), true
+	}
+	// a higher level may cascade before the first slot of a lower level
+	// comes up, e.g. a key placed on level 1 early and one placed on level 0
+	// later, so take the first of all levels
+	next, found := int64(0), false
+	for level := range wheelLevels {
+		if w.counts[level] == 0 {
+			continue
+		}
+		// the slot that comes up first holds the earliest keys of the level
+		shift := wheelBits * level
+		for i := int64(1); i <= wheelSlots; i++ {
+			t := ((w.cur >> shift) + i) << shift
+			if w.heads[level*wheelSlots+int((t>>shift)&wheelMask)] != nilNode {
+				if !found || t < next {
+					next, found = t, true
+				}
+				break
+			}
+		}
+	}
+	if !found {
+		return time.Time{}, false // unreachable, counts are out of sync
+	}
+	return w.timeOf(next), true
+}
+
+func (w *TimingWheel[K]) Reset() {
+	for i := range w.heads {
+		w.heads[i] = nilNode
+	}
+	clear(w.counts[:])
+	clear(w.keys)
+	w.ready = 0
+	w.nodes = w.nodes[:0]
+	w.free = nilNode
+	w.cur = floorDiv(w.now().UnixNano(), w.tick)
+}
+
+// KeyOverhead estimates the bytes spent per key: its node and index entry.
+func (w *TimingWheel[K]) KeyOverhead() int64 {
+	return sizer.Static[wheelNode[K]]() + sizer.MapEntry[K, int32]()
+}
+
+// SetNow sets the internal `now` function and resets the wheel, so call it
+// before scheduling keys. Useful for deterministic tests.
+func (w *TimingWheel[K]) SetNow(now func() time.Time) {
+	w.now = now
+	w.Reset()
+}
+
+// advance turns the wheel tick by tick up to target, cascading higher levels
+// and moving due keys to the ready list. Stretches in which the lower levels
+// are empty are skipped.
+func (w *TimingWheel[K]) advance(target int64) {
+	for w.cur < target {
+		level := 0
+		for level < wheelLevels && w.counts[level] == 0 {
+			level++
+		}
+		if level == wheelLevels {
+			w.cur = target // nothing scheduled
+			return
+		}
+		// levels below level are empty, nothing happens before the next
+		// multiple of 64^level
+		step := int64(1) << (wheelBits * level)
+		t := (floorDiv(w.cur, step) + 1) * step
+		if t > target {
+			w.cur = target
+			return
+		}
+		w.cur = t
+		for l := wheelLevels - 1; l > 0; l-- {
+			shift := wheelBits * l
+			if t&(1<<shift-1) == 0 {
+				w.cascade(l*wheelSlots + int((t>>shift)&wheelMask))
+			}
+		}
+		w.cascade(int(t & wheelMask))
+	}
+}
+
+// cascade places the nodes of list again, relative to the current tick.
+func (w *TimingWheel[K]) cascade(list int) {
+	i := w.heads[list]
+	for i != nilNode {
+		next := w.nodes[i].next
+		w.unlink(i)
+		w.place(i)
+		i = next
+	}
+}
+
+// place links node i in the slot for its deadline.
+func (w *TimingWheel[K]) place(i int32) {
+	at := w.nodes[i].at
+	delta := at - w.cur
+	if delta <= 0 {
+		w.link(i, readyList)
+		return
+	}
+	if delta >= wheelSpan {
+		at = w.cur + wheelSpan - 1 // parked, placed again when it cascades
+		delta = wheelSpan - 1
+	}
+	level := 0
+	for delta >= 1<<(wheelBits*(level+1)) {
+		level++
+	}
+	w.link(i, level*wheelSlots+int((at>>(wheelBits*level))&wheelMask))
+}
+
+func (w *TimingWheel[K]) link(i int32, list int) {
+	n := &w.nodes[i]
+	n.list = int32(list)
+	n.prev = nilNode
+	n.next = w.heads[list]
+	if n.next != nilNode {
+		w.nodes[n.next].prev = i
+	}
+	w.heads[list] = i
+	w.count(list, 1)
+}
+
+func (w *TimingWheel[K]) unlink(i int32) {
+	n := &w.nodes[i]
+	if n.prev != nilNode {
+		w.nodes[n.prev].next = n.next
+	} else {
+		w.heads[n.list] = n.next
+	}
+	if n.next != nilNode {
+		w.nodes[n.next].prev = n.prev
+	}
+	w.count(int(n.list), -1)
+}
+
+func (w *TimingWheel[K]) count(list int, delta int) {
+	if list == readyList {
+		w.ready += delta
+	} else {
+		w.counts[list/wheelSlots] += delta
+	}
+}
+
+func (w *TimingWheel[K]) alloc() int32 {
+	if w.free != nilNode {
+		i := w.free
+		w.free = w.nodes[i].next
+		return i
+	}
+	w.nodes = append(w.nodes, wheelNode[K]{})
+	return int32(len(w.nodes) - 1)
+}
+
+func (w *TimingWheel[K]) release(i int32) {
+	delete(w.keys, w.nodes[i].k)
+	w.nodes[i] = wheelNode[K]{list: nilNode, prev: nilNode, next: w.free}
+	w.free = i
+}
+
+// tickOf returns the first tick at or after t.
+func (w *TimingWheel[K]) tickOf(t time.Time) int64 {
+	return -floorDiv(-t.UnixNano(), w.tick)
+}
+
+func (w *TimingWheel[K]) timeOf(tick int64) time.Time {
+	return time.Unix(0, tick*w.tick)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,275 @@
+package ttl_queue
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestWheel(tick time.Duration) (*TimingWheel[int], time.Time) {
+	n := time.Unix(1_700_000_000, 0)
+	w := NewTimingWheel[int](tick)
+	w.SetNow(func() time.Time { return n })
+	return w, n
+}
+
+func TestTimingWheel_PopExpired(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	w.Schedule(1, n.Add(5*time.Millisecond))
+	w.Schedule(2, n.Add(5*time.Millisecond+time.Microsecond))
+	w.Schedule(3, n.Add(time.Second))
+
+	if got := w.PopExpired(nil, n.Add(4*time.Millisecond), 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+	if got := w.PopExpired(nil, n.Add(5*time.Millisecond), 0); !slices.Equal(got, []int{1}) {
+		t.Errorf("expected [1], got %v", got)
+	}
+	// rounded up to the next tick
+	if got := w.PopExpired(nil, n.Add(5*time.Millisecond+time.Microsecond), 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+	if got := w.PopExpired(nil, n.Add(6*time.Millisecond), 0); !slices.Equal(got, []int{2}) {
+		t.Errorf("expected [2], got %v", got)
+	}
+	if l := w.Len(); l != 1 {
+		t.Errorf("expected len=1, got %d", l)
+	}
+	// cascades down from a higher level
+	if got := w.PopExpired(nil, n.Add(time.Second), 0); !slices.Equal(got, []int{3}) {
+		t.Errorf("expected [3], got %v", got)
+	}
+	if l := w.Len(); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+}
+
+func TestTimingWheel_PopExpired_Limit(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	for i := range 10 {
+		w.Schedule(i, n.Add(-time.Second))
+	}
+
+	got := w.PopExpired(nil, n, 4)
+	if len(got) != 4 {
+		t.Errorf("expected 4 keys, got %d", len(got))
+	}
+	got = w.PopExpired(got, n, 0)
+	slices.Sort(got)
+	if !slices.Equal(got, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("expected all keys once, got %v", got)
+	}
+}
+
+func TestTimingWheel_Reschedule_Cancel(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	if w.Reschedule(1, n) || w.Cancel(1) {
+		t.Errorf("expected false for a missing key")
+	}
+	w.Schedule(1, n.Add(time.Millisecond))
+	w.Schedule(2, n.Add(time.Millisecond))
+	if !w.Reschedule(1, n.Add(time.Hour)) {
+		t.Errorf("expected true, got false")
+	}
+	if !w.Cancel(2) {
+		t.Errorf("expected true, got false")
+	}
+	if got := w.PopExpired(nil, n.Add(time.Minute), 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+	if got := w.PopExpired(nil, n.Add(time.Hour), 0); !slices.Equal(got, []int{1}) {
+		t.Errorf("expected [1], got %v", got)
+	}
+}
+
+func TestTimingWheel_ReusesNodes(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	for i := range 100 {
+		w.Schedule(i, n.Add(time.Second))
+	}
+	for i := range 100 {
+		w.Cancel(i)
+	}
+	for i := range 100 {
+		w.Schedule(i+100, n.Add(time.Second))
+	}
+	if l := len(w.nodes); l != 100 {
+		t.Errorf("expected 100 nodes, got %d", l)
+	}
+}
+
+// Deadlines beyond the span of all levels are parked until they're in range.
+func TestTimingWheel_Parked(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	span := time.Duration(wheelSpan) * time.Millisecond
+	w.Schedule(1, n.Add(3*span))
+
+	if got := w.PopExpired(nil, n.Add(2*span), 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+	if got := w.PopExpired(nil, n.Add(3*span), 0); !slices.Equal(got, []int{1}) {
+		t.Errorf("expected [1], got %v", got)
+	}
+}
+
+func TestTimingWheel_NextDeadline(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	if _, ok := w.NextDeadline(); ok {
+		t.Errorf("expected false on an empty wheel")
+	}
+	w.Schedule(1, n.Add(10*time.Second))
+	w.Schedule(2, n.Add(30*time.Millisecond))
+	if at, _ := w.NextDeadline(); !at.Equal(n.Add(30 * time.Millisecond)) {
+		t.Errorf("expected +30ms, got %v", at.Sub(n))
+	}
+
+	w.Cancel(2)
+	at, _ := w.NextDeadline()
+	if at.After(n.Add(10*time.Second)) || !at.After(n) {
+		t.Errorf("expected a deadline in (0, 10s], got %v", at.Sub(n))
+	}
+
+	w.Schedule(3, n.Add(-time.Second))
+	w.PopExpired(nil, n, 0)
+	w.Schedule(4, n.Add(-time.Second))
+	if at, _ := w.NextDeadline(); at.After(n) {
+		t.Errorf("expected expired keys to be due now, got %v", at.Sub(n))
+	}
+}
+
+// A key on a higher level can be due before the first key of a lower level.
+func TestTimingWheel_NextDeadline_Levels(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	w.Schedule(1, n.Add(64*time.Millisecond)) // level 1
+	w.PopExpired(nil, n.Add(60*time.Millisecond), 0)
+	w.Schedule(2, n.Add(100*time.Millisecond)) // level 0
+	if at, _ := w.NextDeadline(); at.After(n.Add(64 * time.Millisecond)) {
+		t.Errorf("expected a deadline <= 64ms, got %v", at.Sub(n))
+	}
+	if keys := w.PopExpired(nil, n.Add(64*time.Millisecond), 0); len(keys) != 1 || keys[0] != 1 {
+		t.Errorf("expected [1], got %v", keys)
+	}
+}
+
+func TestTimingWheel_Reset(t *testing.T) {
+	w, n := newTestWheel(time.Millisecond)
+	w.Schedule(1, n)
+	w.Schedule(2, n.Add(time.Minute))
+	w.Reset()
+
+	if l := w.Len(); l != 0 {
+		t.Errorf("expected len=0, got %d", l)
+	}
+	if got := w.PopExpired(nil, n.Add(time.Hour), 0); len(got) != 0 {
+		t.Errorf("expected nothing expired, got %v", got)
+	}
+}
+
+// Keys are never popped early and at most one tick late.
+func TestTimingWheel_Random(t *testing.T) {
+	const tick = time.Millisecond
+	w, n := newTestWheel(tick)
+	r := rand.New(rand.NewPCG(1, 2))
+	deadlines := make(map[int]time.Time)
+	for i := range 5000 {
+		// spread over all levels
+		d := time.Duration(r.Int64N(int64(1) << r.IntN(34)))
+		deadlines[i] = n.Add(d)
+		w.Schedule(i, deadlines[i])
+	}
+	for i := range 500 {
+		if i%2 == 0 {
+			w.Cancel(i)
+			delete(deadlines, i)
+		} else {
+			deadlines[i] = n.Add(time.Duration(r.Int64N(int64(time.Hour))))
+			w.Reschedule(i, deadlines[i])
+		}
+	}
+
+	now := n
+	for len(deadlines) > 0 {
+		if at, ok := w.NextDeadline(); !ok {
+			t.Fatalf("expected a deadline, %d keys left", len(deadlines))
+		} else {
+			for k, d := range deadlines {
+				if !d.After(at.Add(-tick)) {
+					t.Fatalf("key=%d expires at %v, before next deadline %v", k, d.Sub(n), at.Sub(n))
+				}
+			}
+		}
+		now = now.Add(time.Duration(r.Int64N(int64(1) << r.IntN(34))))
+		for _, k := range w.PopExpired(nil, now, 0) {
+			d, ok := deadlines[k]
+			if !ok {
+				t.Fatalf("key=%d popped twice or after cancel", k)
+			}
+			if d.After(now) {
+				t.Fatalf("key=%d popped %v early", k, d.Sub(now))
+			}
+			delete(deadlines, k)
+		}
+		for k, d := range deadlines {
+			if !d.After(now.Add(-tick)) {
+				t.Fatalf("key=%d not popped %v after its deadline", k, now.Sub(d))
+			}
+		}
+		if l := w.Len(); l != len(deadlines) {
+			t.Fatalf("expected len=%d, got %d", len(deadlines), l)
+		}
+	}
+}
+
+func TestTimingWheel_KeyOverhead(t *testing.T) {
+	w := NewTimingWheel[int](0)
+	// node + map entry
+	if o := w.KeyOverhead(); o <= 32 {
+		t.Errorf("expected overhead >32, got %d", o)
+	}
+	if w.tick != int64(DefaultTick) {
+		t.Errorf("expected tick=%v, got %v", DefaultTick, time.Duration(w.tick))
+	}
+}
+
+// Both indexes behave the same, apart from the order expired keys are popped
+// in.
+func TestExpiryIndex(t *testing.T) {
+	for _, typ := range []IndexType{TypeHeap, TypeTimingWheel} {
+		t.Run(string(typ), func(t *testing.T) {
+			idx, ok := NewIndex[int](typ)
+			if !ok {
+				t.Fatalf("expected index for %s", typ)
+			}
+			n := time.Now()
+			idx.Schedule(1, n.Add(-time.Second))
+			idx.Schedule(2, n.Add(-time.Second))
+			idx.Schedule(3, n.Add(time.Hour))
+			idx.Schedule(2, n.Add(time.Hour)) // moved
+
+			if got := idx.PopExpired(nil, n, 0); !slices.Equal(got, []int{1}) {
+				t.Errorf("expected [1], got %v", got)
+			}
+			// the wheel rounds up to its tick
+			if at, ok := idx.NextDeadline(); !ok || at.After(n.Add(time.Hour+DefaultTick)) {
+				t.Errorf("expected deadline <= 1h, got %v", at.Sub(n))
+			}
+			if !idx.Cancel(3) {
+				t.Errorf("expected true, got false")
+			}
+			if !idx.Reschedule(2, n.Add(-time.Second)) {
+				t.Errorf("expected true, got false")
+			}
+			if got := idx.PopExpired(nil, n, 0); !slices.Equal(got, []int{2}) {
+				t.Errorf("expected [2], got %v", got)
+			}
+			if idx.Len() != 0 {
+				t.Errorf("expected len=0, got %d", idx.Len())
+			}
+		})
+	}
+
+	if _, ok := NewIndex[int]("unknown"); ok {
+		t.Errorf("expected false for an unknown type")
+	}
+}
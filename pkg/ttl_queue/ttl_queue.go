@@ -3,6 +3,8 @@ package ttl_queue
 import (
 	"container/heap"
 	"time"
+
+	"github.com/jeltjongsma/go-cache/pkg/sizer"
 )
 
 type Entry[K comparable] struct {
@@ -161,6 +163,60 @@ func (t *TTLQueue[K]) Reset() {
 	t.seq = 0
 }
 
+// Schedule lets k expire at the given time, moving it if it is already
+// queued.
+func (t *TTLQueue[K]) Schedule(k K, at time.Time) {
+	if t.Reschedule(k, at) {
+		return
+	}
+	heap.Push(t, &Entry[K]{K: k, ExpiresAt: at})
+}
+
+// Reschedule moves k to the given time, returns false if k isn't queued.
+func (t *TTLQueue[K]) Reschedule(k K, at time.Time) bool {
+	entry, ok := t.keys[k]
+	if !ok {
+		return false
+	}
+	entry.ExpiresAt = at
+	t.seq++
+	entry.seq = t.seq
+	heap.Fix(t, entry.index)
+	return true
+}
+
+// Cancel is Remove, for ExpiryIndex.
+func (t *TTLQueue[K]) Cancel(k K) bool {
+	return t.Remove(k)
+}
+
+// PopExpired pops up to limit keys that expired at now, earliest first, and
+// appends them to dst. limit <= 0 pops all of them.
+func (t *TTLQueue[K]) PopExpired(dst []K, now time.Time, limit int) []K {
+	for n := 0; limit <= 0 || n < limit; n++ {
+		if len(t.queue) == 0 || t.queue[0].ExpiresAt.After(now) {
+			break
+		}
+		dst = append(dst, t.PopMin().K)
+	}
+	return dst
+}
+
+// NextDeadline returns when the first entry of the queue expires.
+func (t *TTLQueue[K]) NextDeadline() (time.Time, bool) {
+	e, ok := t.Peek()
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.ExpiresAt, true
+}
+
+// KeyOverhead estimates the bytes spent per key: its entry, heap slot and
+// index entry.
+func (t *TTLQueue[K]) KeyOverhead() int64 {
+	return sizer.Static[Entry[K]]() + sizer.Static[*Entry[K]]() + sizer.MapEntry[K, *Entry[K]]()
+}
+
 // SetNow sets the internal `now` function.
 // Useful for deterministic tests.
 func (t *TTLQueue[K]) SetNow(now func() time.Time) {
@@ -1,6 +1,7 @@
 package ttl_queue
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -65,3 +66,90 @@ func BenchmarkTTLQueue_Push(b *testing.B) {
 		q.Push(&entries[i&mask])
 	}
 }
+
+// The benchmarks below compare the ExpiryIndex implementations.
+
+const benchKeys = 1 << 16
+
+var benchIndexes = []IndexType{TypeHeap, TypeTimingWheel, TypeNone}
+
+func newFullIndex(b *testing.B, t IndexType, n time.Time) ExpiryIndex[int] {
+	idx, ok := NewIndex[int](t)
+	if !ok {
+		b.Fatalf("unknown index %s", t)
+	}
+	for i := range benchKeys {
+		idx.Schedule(i, n.Add(time.Duration(i)*time.Millisecond))
+	}
+	return idx
+}
+
+func BenchmarkExpiryIndex_Schedule(b *testing.B) {
+	for _, t := range benchIndexes {
+		b.Run(string(t), func(b *testing.B) {
+			n := time.Now()
+			idx := newFullIndex(b, t, n)
+
+			b.ResetTimer()
+			for i := range b.N {
+				idx.Schedule(benchKeys+i, n.Add(5*time.Minute))
+			}
+		})
+	}
+}
+
+// Set on an existing key moves its deadline.
+func BenchmarkExpiryIndex_Reschedule(b *testing.B) {
+	for _, t := range benchIndexes {
+		b.Run(string(t), func(b *testing.B) {
+			n := time.Now()
+			idx := newFullIndex(b, t, n)
+
+			b.ResetTimer()
+			for i := range b.N {
+				idx.Reschedule(i%benchKeys, n.Add(5*time.Minute+time.Duration(i)))
+			}
+		})
+	}
+}
+
+func BenchmarkExpiryIndex_Cancel(b *testing.B) {
+	for _, t := range benchIndexes {
+		b.Run(string(t), func(b *testing.B) {
+			n := time.Now()
+			idx := newFullIndex(b, t, n)
+
+			b.ResetTimer()
+			for i := range b.N {
+				k := i % benchKeys
+				idx.Cancel(k)
+				b.StopTimer()
+				idx.Schedule(k, n.Add(time.Duration(k)*time.Millisecond))
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// Steady state: every key is scheduled once and popped when it expires.
+func BenchmarkExpiryIndex_ScheduleExpire(b *testing.B) {
+	for _, t := range benchIndexes {
+		for _, batch := range []int{1, 64} {
+			b.Run(fmt.Sprintf("%s/batch=%d", t, batch), func(b *testing.B) {
+				n := time.Now()
+				idx := newFullIndex(b, t, n)
+				buf := make([]int, 0, batch)
+
+				b.ResetTimer()
+				for i := range b.N {
+					// keys expire 1ms apart, so time moves 1ms per key
+					now := n.Add(time.Duration(i) * time.Millisecond)
+					idx.Schedule(benchKeys+i, now.Add(benchKeys*time.Millisecond))
+					if i%batch == 0 {
+						buf = idx.PopExpired(buf[:0], now, 0)
+					}
+				}
+			})
+		}
+	}
+}
@@ -401,3 +401,11 @@ func TestSetNow(t *testing.T) {
 		t.Errorf("now not set")
 	}
 }
+
+func TestKeyOverhead(t *testing.T) {
+	q := NewTTLQueue[int](100)
+	// entry, heap slot and index entry are all larger than the key
+	if o := q.KeyOverhead(); o <= 3*8 {
+		t.Errorf("expected overhead > 24, got %d", o)
+	}
+}
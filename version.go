@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict matches every *VersionConflictError with errors.Is.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNotStored is returned by SetIfVersion when the cache rejected the
+// value, for example because its cost exceeds the budget.
+var ErrNotStored = errors.New("value not stored")
+
+// VersionConflictError is returned by SetIfVersion when the entry changed
+// since its version was read.
+type VersionConflictError struct {
+	Expected uint64 // version passed to SetIfVersion
+	Actual   uint64 // current version, 0 when the key was removed
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.Actual == 0 {
+		return fmt.Sprintf("version conflict: expected %d, key was removed", e.Expected)
+	}
+	return fmt.Sprintf("version conflict: expected %d, got %d", e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// GetWithVersion is like Get, but also returns the version of the entry for
+// SetIfVersion. Versions change on every write, so a key never gets the same
+// version twice.
+func (c *Cache[K, V]) GetWithVersion(key K) (val V, version uint64, hit bool) {
+	if c.closed() {
+		return
+	}
+	shard, _ := c.shardFor(key)
+	var refresh bool
+	val, version, hit, refresh = shard.GetWithVersion(key)
+	if refresh && c.loader != nil {
+		c.refresh(key)
+	}
+	if hit {
+		c.stats.Hits.Add(1)
+	} else {
+		c.stats.Misses.Add(1)
+	}
+	return
+}
+
+// SetIfVersion stores val like Set, but only if the version of key still
+// equals version, like memcached's cas. Returns a *VersionConflictError when
+// the key was written or removed in the meantime, and ErrNotStored when the
+// value was rejected.
+func (c *Cache[K, V]) SetIfVersion(key K, val V, version uint64) error {
+	if c.closed() {
+		return ErrClosed
+	}
+	shard, _ := c.shardFor(key)
+	current, stored, evicted := shard.SetIfVersion(key, val, version)
+	c.stats.Evictions.Add(uint64(evicted))
+	if stored {
+		return nil
+	}
+	if current != version {
+		return &VersionConflictError{Expected: version, Actual: current}
+	}
+	return ErrNotStored
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCache_SetIfVersion(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set("a", 1)
+	val, version, hit := c.GetWithVersion("a")
+	if val != 1 || !hit {
+		t.Fatalf("expected (1, true), got (%d, %v)", val, hit)
+	}
+	if err := c.SetIfVersion("a", 2, version); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = c.SetIfVersion("a", 3, version)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected *VersionConflictError, got %v", err)
+	}
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected errors.Is(err, ErrVersionConflict)")
+	}
+	if conflict.Expected != version || conflict.Actual <= version {
+		t.Errorf("expected (%d, >%d), got (%d, %d)", version, version, conflict.Expected, conflict.Actual)
+	}
+
+	c.Del("a")
+	err = c.SetIfVersion("a", 3, conflict.Actual)
+	if !errors.As(err, &conflict) || conflict.Actual != 0 {
+		t.Errorf("expected conflict with actual=0, got %v", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected len=0, got %d", c.Len())
+	}
+}
+
+func TestCache_SetIfVersion_NotStored(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]().SetNumShards(1).SetMaxCost(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetWeigher(func(k string, v int) int64 { return int64(v) })
+	c.Set("a", 1)
+	_, version, _ := c.GetWithVersion("a")
+
+	if err := c.SetIfVersion("a", 100, version); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected ErrNotStored, got %v", err)
+	}
+}
+
+// Optimistic read-modify-write: every increment eventually succeeds.
+func TestCache_SetIfVersion_Concurrent(t *testing.T) {
+	c, err := NewCache[string, int](NewOptions[string]())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Set("a", 0)
+	const goroutines, iterations = 8, 200
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				for {
+					val, version, _ := c.GetWithVersion("a")
+					err := c.SetIfVersion("a", val+1, version)
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrVersionConflict) {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get("a"); v != goroutines*iterations {
+		t.Errorf("expected %d, got %d", goroutines*iterations, v)
+	}
+}